@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+// Placeholders expanded in RECORD_COMMENT.
+const (
+	CommentPlaceholderTimestamp = "{timestamp}"
+	CommentPlaceholderInstance  = "{instance}"
+)
+
+// commentAccount is the account PowerDNS shows for controller comments.
+const commentAccount = "k8s-external-ip-powerdns"
+
+// recordOptions returns the RRset options applied to every write, which is
+// currently the RECORD_COMMENT comment when template is set.
+func recordOptions(template string) []func(*powerdns.RRset) {
+	if template == "" {
+		return nil
+	}
+
+	now := time.Now()
+	content := expandComment(template, now, commentInstance())
+	account := commentAccount
+	modifiedAt := uint64(now.Unix())
+	return []func(*powerdns.RRset){
+		powerdns.WithComments(powerdns.Comment{Content: &content, Account: &account, ModifiedAt: &modifiedAt}),
+	}
+}
+
+// expandComment fills in the timestamp and instance placeholders.
+func expandComment(template string, now time.Time, instance string) string {
+	return strings.NewReplacer(
+		CommentPlaceholderTimestamp, now.UTC().Format(time.RFC3339),
+		CommentPlaceholderInstance, instance,
+	).Replace(template)
+}
+
+// commentInstance identifies this controller instance: the Pod name when
+// running in Kubernetes, the hostname otherwise.
+func commentInstance() string {
+	if name := getEnv("POD_NAME"); name != "" {
+		return name
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return "unknown"
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+func TestExpandComment(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	got := expandComment("managed by k3s-external-ip-powerdns ({instance}) at {timestamp}", now, "controller-0")
+	want := "managed by k3s-external-ip-powerdns (controller-0) at 2024-05-01T10:30:00Z"
+	if got != want {
+		t.Errorf("expandComment() = %q, want %q", got, want)
+	}
+}
+
+func TestRecordOptions(t *testing.T) {
+	if options := recordOptions(""); options != nil {
+		t.Errorf("recordOptions() without RECORD_COMMENT = %d options, want none", len(options))
+	}
+
+	t.Setenv("POD_NAME", "controller-0")
+	rrset := &powerdns.RRset{}
+	for _, option := range recordOptions("managed by {instance}") {
+		option(rrset)
+	}
+	if len(rrset.Comments) != 1 || *rrset.Comments[0].Content != "managed by controller-0" {
+		t.Errorf("RRset comments = %+v, want one comment naming the instance", rrset.Comments)
+	}
+}
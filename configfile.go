@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// fileValues holds settings read from CONFIG_FILE, keyed by the environment
+// variable they provide a value for. Environment variables and flags take
+// precedence over them.
+var fileValues = map[string]string{}
+
+// loadConfigFile reads a YAML file whose keys are the lowercased names of
+// the supported environment variables, e.g. "dns_zone". Lists are joined
+// with commas, matching the environment variable format.
+func loadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseConfigFile(data)
+}
+
+// parseConfigFile converts YAML config file contents into environment
+// variable values, rejecting unknown keys so typos don't go unnoticed.
+func parseConfigFile(data []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	// Keep numbers as written so large integers don't turn into floats
+	useNumber := func(d *json.Decoder) *json.Decoder {
+		d.UseNumber()
+		return d
+	}
+	if err := yaml.Unmarshal(data, &raw, useNumber); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	known := make(map[string]bool, len(configFlags))
+	for _, cf := range configFlags {
+		known[cf.envVar] = true
+	}
+
+	values := make(map[string]string, len(raw))
+	var unknown []string
+	for key, value := range raw {
+		envVar := strings.ToUpper(key)
+		if !known[envVar] || envVar == "CONFIG_FILE" {
+			unknown = append(unknown, key)
+			continue
+		}
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[envVar] = strings.Join(items, ",")
+		case map[string]interface{}:
+			return nil, fmt.Errorf("config file key %q must be a scalar or a list", key)
+		default:
+			values[envVar] = fmt.Sprint(v)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown config file keys: %s", strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
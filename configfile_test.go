@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	data := []byte(`
+powerdns_url: http://powerdns:8081
+dns_zone: example.com
+dns_record:
+  - cluster.example.com
+  - ingress.example.com
+dns_ttl: 5m
+max_records: 1000000
+sync_jitter: 0.2
+dry_run: true
+node_selector:
+`)
+
+	got, err := parseConfigFile(data)
+	if err != nil {
+		t.Fatalf("parseConfigFile() error = %v", err)
+	}
+
+	want := map[string]string{
+		"POWERDNS_URL": "http://powerdns:8081",
+		"DNS_ZONE":     "example.com",
+		"DNS_RECORD":   "cluster.example.com,ingress.example.com",
+		"DNS_TTL":      "5m",
+		"MAX_RECORDS":  "1000000",
+		"SYNC_JITTER":  "0.2",
+		"DRY_RUN":      "true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConfigFile() = %v, want %v", got, want)
+	}
+}
+
+func TestParseConfigFileRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unknown key", "dns_zoen: example.com\n"},
+		{"nested config file", "config_file: other.yaml\n"},
+		{"nested map", "dns_zone:\n  name: example.com\n"},
+		{"not a mapping", "- dns_zone\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseConfigFile([]byte(tt.data)); err == nil {
+				t.Errorf("parseConfigFile(%q) should fail", tt.data)
+			}
+		})
+	}
+}
+
+func TestConfigFilePrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("dns_ttl: 10m\nsync_interval: 2m\nmax_records: 3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+
+	previous := fileValues
+	fileValues = values
+	t.Cleanup(func() { fileValues = previous })
+
+	setRequiredEnv(t)
+	t.Setenv("SYNC_INTERVAL", "45s")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.TTL != 600 {
+		t.Errorf("TTL = %d, want 600 from the file", config.TTL)
+	}
+	if config.SyncInterval.String() != "45s" {
+		t.Errorf("SyncInterval = %v, want 45s from the environment", config.SyncInterval)
+	}
+	if config.MaxRecords != 3 {
+		t.Errorf("MaxRecords = %d, want 3 from the file", config.MaxRecords)
+	}
+}
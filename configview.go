@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// redactedValue replaces the value of a set secret in /config.
+const redactedValue = "REDACTED"
+
+// effectiveConfig is the configuration in use, as served by /config. It is
+// replaced when a reload succeeds.
+var effectiveConfig atomic.Pointer[Config]
+
+// configHandler serves the effective configuration as JSON, keyed by Config
+// field name, with secrets redacted, so that support can check how the
+// environment, flags and config file were interpreted.
+func configHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := effectiveConfig.Load()
+		if config == nil {
+			http.Error(w, "configuration not loaded", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(configView(config)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// configView returns the fields of config as JSON-friendly values. Durations
+// are written like "30s", networks in CIDR notation and IPs as text.
+func configView(config *Config) map[string]any {
+	value := reflect.ValueOf(config).Elem()
+	view := make(map[string]any, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Name
+		switch field := value.Field(i).Interface().(type) {
+		case string:
+			if secretConfigFields[name] && field != "" {
+				view[name] = redactedValue
+			} else {
+				view[name] = field
+			}
+		case time.Duration:
+			view[name] = field.String()
+		case []*net.IPNet:
+			networks := make([]string, 0, len(field))
+			for _, network := range field {
+				networks = append(networks, network.String())
+			}
+			view[name] = networks
+		case []IPAddress:
+			view[name] = ipStrings(field)
+		default:
+			view[name] = field
+		}
+	}
+	return view
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigHandler(t *testing.T) {
+	config := newUpdateTestConfig()
+	config.PowerDNSAPIKey = "secret"
+	config.SyncInterval = 30 * time.Second
+	config.IncludeCIDRs, _ = parseCIDRs("203.0.113.0/24")
+	config.ExtraIPs, _ = parseIPAddresses("192.0.2.1")
+
+	previous := effectiveConfig.Swap(config)
+	t.Cleanup(func() { effectiveConfig.Store(previous) })
+
+	recorder := httptest.NewRecorder()
+	configHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+
+	var view map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &view); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, recorder.Body)
+	}
+	for field, want := range map[string]any{
+		"PowerDNSAPIKey": redactedValue,
+		"WebhookURL":     "",
+		"SyncInterval":   "30s",
+		"TTL":            float64(DefaultTTL),
+		"DNSZone":        "example.com.",
+		"IncludeCIDRs":   []any{"203.0.113.0/24"},
+		"ExtraIPs":       []any{"192.0.2.1"},
+	} {
+		if got, _ := json.Marshal(view[field]); string(got) != mustJSON(t, want) {
+			t.Errorf("%s = %s, want %s", field, got, mustJSON(t, want))
+		}
+	}
+	if body := recorder.Body.String(); strings.Contains(body, "secret") {
+		t.Errorf("/config leaks the API key:\n%s", body)
+	}
+}
+
+func TestConfigHandlerNotLoaded(t *testing.T) {
+	previous := effectiveConfig.Swap(nil)
+	t.Cleanup(func() { effectiveConfig.Store(previous) })
+
+	recorder := httptest.NewRecorder()
+	configHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", recorder.Code)
+	}
+}
+
+func mustJSON(t *testing.T, value any) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,83 @@
+package main
+
+import "time"
+
+// DefaultMinWriteInterval is how long node changes must stop before the
+// triggered sync runs.
+const DefaultMinWriteInterval = 2 * time.Second
+
+// DebounceMaxWaitFactor bounds, in intervals after the first event of a
+// burst, how long events that keep arriving can hold back the sync.
+const DebounceMaxWaitFactor = 4
+
+// debouncer coalesces bursts of node change events into a single sync. Each
+// event restarts the timer, so the sync runs once no event has arrived for
+// the interval, and a flapping node costs one write per quiet period rather
+// than one per change. A node flapping faster than the interval would never
+// leave a quiet period, so the sync runs at the latest DebounceMaxWaitFactor
+// intervals after the first event of the burst.
+type debouncer struct {
+	interval time.Duration
+	timer    *time.Timer
+	pending  bool
+	deadline time.Time // Latest time the pending sync runs
+}
+
+// newDebouncer returns a debouncer with no pending event.
+func newDebouncer(interval time.Duration) *debouncer {
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	return &debouncer{interval: interval, timer: timer}
+}
+
+// event records a change. It reports true when the sync should run right
+// away because debouncing is disabled; otherwise the sync is due when C fires.
+func (d *debouncer) event() bool {
+	if d.interval <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if !d.pending {
+		d.deadline = now.Add(DebounceMaxWaitFactor * d.interval)
+	}
+	wait := d.interval
+	if remaining := d.deadline.Sub(now); remaining < wait {
+		wait = remaining
+	}
+	d.stop()
+	d.timer.Reset(wait)
+	d.pending = true
+	return false
+}
+
+// C fires once the events of a burst have stopped for the interval, or the
+// maximum wait has passed.
+func (d *debouncer) C() <-chan time.Time {
+	return d.timer.C
+}
+
+// fired marks the pending sync as started after C fired.
+func (d *debouncer) fired() {
+	d.pending = false
+}
+
+// cancel drops the pending sync, e.g. because another sync covers it.
+func (d *debouncer) cancel() {
+	if d.pending {
+		d.stop()
+		d.pending = false
+	}
+}
+
+// stop stops the timer and drains a tick that was not received yet.
+func (d *debouncer) stop() {
+	if !d.timer.Stop() {
+		select {
+		case <-d.timer.C:
+		default:
+		}
+	}
+}
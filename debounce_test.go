@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebouncerCoalescesBurst(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	d := newDebouncer(interval)
+
+	start := time.Now()
+	var last time.Time
+	for i := 0; i < 5; i++ {
+		if d.event() {
+			t.Fatal("event() = true, want the sync delayed")
+		}
+		last = time.Now()
+		time.Sleep(interval / 5)
+	}
+
+	select {
+	case <-d.C():
+		d.fired()
+	case <-time.After(time.Second):
+		t.Fatal("debouncer did not fire")
+	}
+	if elapsed := time.Since(last); elapsed < interval {
+		t.Errorf("fired %v after the last event, want at least %v", elapsed, interval)
+	}
+	if elapsed := time.Since(start); elapsed < interval+4*interval/5 {
+		t.Errorf("fired %v after the first event, want the timer restarted by each event", elapsed)
+	}
+
+	select {
+	case <-d.C():
+		t.Error("debouncer fired twice for one burst")
+	case <-time.After(2 * interval):
+	}
+}
+
+func TestDebouncerMaxWait(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	d := newDebouncer(interval)
+
+	// A node flapping faster than the interval still gets a sync once the
+	// maximum wait has passed
+	start := time.Now()
+	for time.Since(start) < 2*DebounceMaxWaitFactor*interval {
+		d.event()
+		select {
+		case <-d.C():
+			d.fired()
+			if elapsed := time.Since(start); elapsed < DebounceMaxWaitFactor*interval {
+				t.Errorf("fired %v after the first event, want no earlier than the maximum wait", elapsed)
+			}
+			return
+		case <-time.After(interval / 2):
+		}
+	}
+	t.Error("debouncer never fired while events kept arriving")
+}
+
+func TestDebouncerCancel(t *testing.T) {
+	d := newDebouncer(20 * time.Millisecond)
+	d.event()
+	d.cancel()
+
+	select {
+	case <-d.C():
+		t.Error("debouncer fired after cancel")
+	case <-time.After(60 * time.Millisecond):
+	}
+}
+
+func TestDebouncerDisabled(t *testing.T) {
+	d := newDebouncer(0)
+	if !d.event() {
+		t.Error("event() = false with a zero interval, want the sync to run at once")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// runDump prints the A/AAAA (and MANAGE_TXT) records a sync would publish as zone-file lines
+// to w, without contacting PowerDNS.
+func runDump(ctx context.Context, w io.Writer, clientset kubernetes.Interface, config *Config) error {
+	ips, err := fetchExternalIPs(ctx, clientset, config)
+	if err != nil {
+		return fmt.Errorf("failed to fetch external IPs: %w", err)
+	}
+	slog.Info("Dumping desired records", "ips", len(ips))
+	return dumpRecords(w, config, ips)
+}
+
+// dumpRecords writes the A/AAAA RRsets for ips in BIND zone-file format,
+// one line per value, grouped by zone:
+//
+//	; zone example.com.
+//	cluster.example.com.	300	IN	A	203.0.113.1
+func dumpRecords(w io.Writer, config *Config, ips []IPAddress) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	config = nodeTTLConfig(config, ips)
+
+	ipv4Records, ipv6Records, _ := familyRecordValues(config, ips)
+
+	for _, zoneConfig := range zoneConfigs(config) {
+		zone := validateDNSZone(zoneConfig.DNSZone)
+		fmt.Fprintf(tw, "; zone %s\n", zone)
+		shared := sharedRecordConfig(zoneConfig)
+		for _, record := range zoneConfig.DNSRecords {
+			dumpRRsets(tw, shared, validateDNSRecord(record), ipv4Records, ipv6Records)
+			if zoneConfig.ManageTXT {
+				for _, value := range txtRecordValues(ips) {
+					fmt.Fprintf(tw, "%s\t%d\tIN\t%s\t%s\n", validateDNSRecord(record), recordTTL(zoneConfig, RecordTypeTXT), RecordTypeTXT, value)
+				}
+			}
+		}
+
+		if zoneConfig.DNSRecordA != "" {
+			dumpRRsets(tw, familyRecordConfig(zoneConfig, RecordTypeA), zoneConfig.DNSRecordA, ipv4Records, nil)
+		}
+		if zoneConfig.DNSRecordAAAA != "" {
+			dumpRRsets(tw, familyRecordConfig(zoneConfig, RecordTypeAAAA), zoneConfig.DNSRecordAAAA, nil, ipv6Records)
+		}
+
+		if zoneConfig.PerNodeRecords != "" {
+			perNodeV4 := make(map[string][]string)
+			perNodeV6 := make(map[string][]string)
+			names := make(map[string]bool)
+			for _, ip := range ips {
+				if ip.NodeRecord == "" || !recordInZone(ip.NodeRecord, zone) {
+					continue
+				}
+				names[ip.NodeRecord] = true
+				if ip.IsIPv6 {
+					perNodeV6[ip.NodeRecord] = append(perNodeV6[ip.NodeRecord], ip.String)
+				} else {
+					perNodeV4[ip.NodeRecord] = append(perNodeV4[ip.NodeRecord], ip.String)
+				}
+			}
+			for _, name := range sortedNames(names) {
+				dumpRRsets(tw, zoneConfig, name, perNodeV4[name], perNodeV6[name])
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// dumpRRsets writes the managed A and AAAA records of one name.
+func dumpRRsets(w io.Writer, config *Config, name string, ipv4Records, ipv6Records []string) {
+	if config.ManageA {
+		for _, value := range ipv4Records {
+			fmt.Fprintf(w, "%s\t%d\tIN\t%s\t%s\n", name, recordTTL(config, RecordTypeA), RecordTypeA, value)
+		}
+	}
+	if config.ManageAAAA {
+		for _, value := range ipv6Records {
+			fmt.Fprintf(w, "%s\t%d\tIN\t%s\t%s\n", name, recordTTL(config, RecordTypeAAAA), RecordTypeAAAA, value)
+		}
+	}
+}
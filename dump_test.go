@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDumpRecords(t *testing.T) {
+	config := newUpdateTestConfig()
+	config.DNSRecords = []string{"www.example.com.", "api.example.com"}
+	config.TTLAAAA = 60
+	config.ExtraZones = []ZoneRecords{{Zone: "example.org.", Records: []string{"www.example.org."}}}
+	ips, _ := parseIPAddresses("203.0.113.1,2001:db8::1")
+
+	var out bytes.Buffer
+	if err := dumpRecords(&out, config, ips); err != nil {
+		t.Fatalf("dumpRecords() error = %v", err)
+	}
+
+	want := `; zone example.com.
+www.example.com. 300 IN A 203.0.113.1
+www.example.com. 60 IN AAAA 2001:db8::1
+api.example.com. 300 IN A 203.0.113.1
+api.example.com. 60 IN AAAA 2001:db8::1
+; zone example.org.
+www.example.org. 300 IN A 203.0.113.1
+www.example.org. 60 IN AAAA 2001:db8::1`
+	if got := zoneLines(out.String()); got != want {
+		t.Errorf("dumpRecords() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// zoneLines collapses the column padding of zone-file lines to single spaces.
+func zoneLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestRunDumpPerNodeRecords(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "203.0.113.1"}, nil),
+		newTestNode("node-b", map[string]string{ExternalIPAnnotation: "2001:db8::2"}, nil),
+	)
+	config := newUpdateTestConfig()
+	config.ManageAAAA = false
+	config.PerNodeRecords = "{node}.nodes.example.com."
+
+	var out bytes.Buffer
+	if err := runDump(context.Background(), &out, clientset, config); err != nil {
+		t.Fatalf("runDump() error = %v", err)
+	}
+
+	want := `; zone example.com.
+www.example.com. 300 IN A 203.0.113.1
+node-a.nodes.example.com. 300 IN A 203.0.113.1`
+	if got := zoneLines(out.String()); got != want {
+		t.Errorf("runDump() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Event reasons emitted when DNS records change.
+const (
+	EventReasonRecordCreated = "DNSRecordCreated"
+	EventReasonRecordUpdated = "DNSRecordUpdated"
+	EventReasonRecordDeleted = "DNSRecordDeleted"
+)
+
+// EventEmitter records Kubernetes Events about DNS changes on the
+// controller's own Pod. A nil *EventEmitter discards all events.
+type EventEmitter struct {
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	pod         *corev1.ObjectReference
+}
+
+var eventEmitter *EventEmitter
+
+// newEventEmitter creates an emitter attached to the given Pod. It returns
+// nil when the Pod is unknown, which disables events.
+func newEventEmitter(clientset kubernetes.Interface, namespace, podName string) *EventEmitter {
+	if namespace == "" || podName == "" {
+		slog.Info("POD_NAME or POD_NAMESPACE not set, Kubernetes Events are disabled")
+		return nil
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events(namespace)})
+
+	return &EventEmitter{
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "k8s-external-ip-powerdns"}),
+		pod:         podReference(namespace, podName),
+	}
+}
+
+// podReference builds the object reference events are attached to.
+func podReference(namespace, name string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+// recordChange emits a Normal event describing a DNS record change.
+func (e *EventEmitter) recordChange(reason, messageFmt string, args ...any) {
+	if e == nil {
+		return
+	}
+	e.recorder.Eventf(e.pod, corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+// Shutdown flushes and stops the event broadcaster.
+func (e *EventEmitter) Shutdown() {
+	if e == nil {
+		return
+	}
+	e.broadcaster.Shutdown()
+}
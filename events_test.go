@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEventEmitterRecordChange(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	emitter := &EventEmitter{recorder: recorder, pod: podReference("tools", "controller")}
+
+	emitter.recordChange(EventReasonRecordUpdated, "Set %s record %s to %d IP(s)", "A", "cluster.example.com.", 2)
+
+	want := "Normal DNSRecordUpdated Set A record cluster.example.com. to 2 IP(s)"
+	if got := <-recorder.Events; got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+}
+
+func TestNewEventEmitterDisabledWithoutPod(t *testing.T) {
+	if emitter := newEventEmitter(fake.NewSimpleClientset(), "", "controller"); emitter != nil {
+		t.Fatal("expected nil emitter when POD_NAMESPACE is unset")
+	}
+
+	// A nil emitter must be safe to use
+	var emitter *EventEmitter
+	emitter.recordChange(EventReasonRecordDeleted, "Deleted %s record %s", "A", "cluster.example.com.")
+	emitter.Shutdown()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Exit codes, so that init scripts and operators can tell why the process
+// stopped without parsing its logs.
+const (
+	// ExitFailure is used for any fatal error without a more specific code.
+	ExitFailure = 1
+	// ExitUsage is used for invalid command line flags, like the flag package.
+	ExitUsage = 2
+	// ExitPermissionDenied is used when the service account lacks the RBAC
+	// permissions to read nodes or services.
+	ExitPermissionDenied = 3
+	// ExitConnectionFailed is used when the Kubernetes API or PowerDNS cannot
+	// be reached or rejects the credentials.
+	ExitConnectionFailed = 4
+)
+
+// fatalWithCode logs msg at error level and exits with code.
+func fatalWithCode(code int, msg string, args ...any) {
+	slog.Error(msg, append(args, "exit_code", code)...)
+	os.Exit(code)
+}
+
+// kubernetesExitCode returns the exit code for a failed startup check
+// against the Kubernetes API: ExitPermissionDenied when RBAC forbids the
+// request, ExitFailure for a missing object and ExitConnectionFailed for
+// anything else, such as an unreachable API server or rejected token.
+func kubernetesExitCode(err error) int {
+	switch {
+	case apierrors.IsForbidden(err):
+		return ExitPermissionDenied
+	case apierrors.IsNotFound(err):
+		return ExitFailure
+	default:
+		return ExitConnectionFailed
+	}
+}
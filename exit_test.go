@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestKubernetesExitCode(t *testing.T) {
+	nodes := schema.GroupResource{Resource: "nodes"}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"forbidden", apierrors.NewForbidden(nodes, "", errors.New("cannot list nodes")), ExitPermissionDenied},
+		{"wrapped forbidden", fmt.Errorf("failed to get service default/ingress: %w", apierrors.NewForbidden(schema.GroupResource{Resource: "services"}, "ingress", errors.New("denied"))), ExitPermissionDenied},
+		{"unauthorized", apierrors.NewUnauthorized("invalid token"), ExitConnectionFailed},
+		{"not found", apierrors.NewNotFound(schema.GroupResource{Resource: "services"}, "ingress"), ExitFailure},
+		{"connection refused", errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), ExitConnectionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kubernetesExitCode(tt.err); got != tt.want {
+				t.Errorf("kubernetesExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// configFlag maps a command-line flag to the environment variable it overrides.
+type configFlag struct {
+	name   string
+	envVar string
+	usage  string
+	isBool bool
+}
+
+var configFlags = []configFlag{
+	{name: "config", envVar: "CONFIG_FILE", usage: "YAML file with settings; environment variables and flags override it"},
+	{name: "powerdns-url", envVar: "POWERDNS_URL", usage: "PowerDNS API base URL, or a comma-separated list of servers to update"},
+	{name: "api-key", envVar: "POWERDNS_API_KEY", usage: "PowerDNS API key"},
+	{name: "api-key-file", envVar: "POWERDNS_API_KEY_FILE", usage: "file containing the PowerDNS API key; takes precedence over --api-key"},
+	{name: "server-id", envVar: "POWERDNS_SERVER_ID", usage: "PowerDNS server id"},
+	{name: "vhost", envVar: "POWERDNS_VHOST", usage: "deprecated alias of --server-id"},
+	{name: "max-retries", envVar: "POWERDNS_MAX_RETRIES", usage: "retries for transient PowerDNS failures"},
+	{name: "powerdns-timeout", envVar: "POWERDNS_TIMEOUT", usage: "timeout of each PowerDNS API request"},
+	{name: "client-cert", envVar: "POWERDNS_CLIENT_CERT", usage: "PEM client certificate for mTLS to PowerDNS"},
+	{name: "client-key", envVar: "POWERDNS_CLIENT_KEY", usage: "PEM private key of the client certificate"},
+	{name: "ca-cert", envVar: "POWERDNS_CA_CERT", usage: "PEM CA bundle used to verify the PowerDNS server certificate"},
+	{name: "user-agent", envVar: "USER_AGENT", usage: "User-Agent header of PowerDNS API requests (default k8s-external-ip-powerdns/<version>)"},
+	{name: "insecure-skip-verify", envVar: "POWERDNS_INSECURE_SKIP_VERIFY", usage: "do not verify the PowerDNS server certificate (insecure, for lab setups only)", isBool: true},
+	{name: "zone", envVar: "DNS_ZONE", usage: "DNS zone to update"},
+	{name: "record", envVar: "DNS_RECORD", usage: "comma-separated DNS record names to update"},
+	{name: "record-prefix", envVar: "RECORD_PREFIX", usage: "labels prepended to every DNS record, e.g. a cluster name"},
+	{name: "record-suffix", envVar: "RECORD_SUFFIX", usage: "labels inserted between every DNS record and the DNS zone"},
+	{name: "zone-records", envVar: "ZONE_RECORDS", usage: "additional zone=record[,record...] pairs, separated by semicolons"},
+	{name: "cname", envVar: "DNS_CNAME", usage: "alias name to maintain as a CNAME pointing at the first DNS record"},
+	{name: "record-a", envVar: "DNS_RECORD_A", usage: "name receiving the A records instead of the DNS records"},
+	{name: "record-aaaa", envVar: "DNS_RECORD_AAAA", usage: "name receiving the AAAA records instead of the DNS records"},
+	{name: "txt-owner-id", envVar: "TXT_OWNER_ID", usage: "instance id recorded in ownership TXT records; records owned by others are never modified"},
+	{name: "record-comment", envVar: "RECORD_COMMENT", usage: "comment set on written RRsets; {timestamp} and {instance} are expanded"},
+	{name: "ttl", envVar: "DNS_TTL", usage: "DNS record TTL"},
+	{name: "ttl-a", envVar: "DNS_TTL_A", usage: "TTL for A records"},
+	{name: "ttl-aaaa", envVar: "DNS_TTL_AAAA", usage: "TTL for AAAA records"},
+	{name: "strict-ttl", envVar: "STRICT_TTL", usage: "fail on TTLs outside 1s-7d instead of clamping them", isBool: true},
+	{name: "sync-interval", envVar: "SYNC_INTERVAL", usage: "interval between syncs"},
+	{name: "sync-jitter", envVar: "SYNC_JITTER", usage: "random extra delay per sync as a fraction of the sync interval, e.g. 0.2"},
+	{name: "failure-retry-interval", envVar: "FAILURE_RETRY_INTERVAL", usage: "first retry delay after a failed sync, doubling up to the sync interval"},
+	{name: "min-write-interval", envVar: "MIN_WRITE_INTERVAL", usage: "quiet period after node changes before the triggered sync, 0 to sync at once"},
+	{name: "removal-grace-period", envVar: "REMOVAL_GRACE_PERIOD", usage: "how long an IP stays published after it disappears, 0 to remove it at once"},
+	{name: "verify-dns", envVar: "VERIFY_DNS", usage: "resolve the records after writing them and warn when the live answer differs", isBool: true},
+	{name: "verify-resolver", envVar: "VERIFY_RESOLVER", usage: "DNS server queried by --verify-dns as host[:port], default the system resolver"},
+	{name: "kubeconfig", envVar: "KUBECONFIG", usage: "path to kubeconfig file"},
+	{name: "k8s-mode", envVar: "K8S_MODE", usage: "force the Kubernetes config source: incluster or kubeconfig"},
+	{name: "nodes-file", envVar: "NODES_FILE", usage: "development only: read nodes from this JSON node list instead of the Kubernetes API"},
+	{name: "k8s-timeout", envVar: "K8S_TIMEOUT", usage: "timeout for Kubernetes API calls"},
+	{name: "node-selector", envVar: "NODE_SELECTOR", usage: "label selector for nodes to include"},
+	{name: "ip-source", envVar: "IP_SOURCE", usage: "comma-separated IP sources: annotation, status, both and/or service"},
+	{name: "per-node-records", envVar: "PER_NODE_RECORDS", usage: "also publish each node's IPs under a name templated from {node}, {label:<key>} or {annotation:<key>}"},
+	{name: "separator", envVar: "SEPARATOR", usage: "additional separator of IPs in the annotation, e.g. ;"},
+	{name: "extra-ips", envVar: "EXTRA_IPS", usage: "comma-separated static IPs always published alongside the node IPs"},
+	{name: "address-types", envVar: "ADDRESS_TYPES", usage: "node status address types for the status source, in order of preference"},
+	{name: "service-namespace", envVar: "SERVICE_NAMESPACE", usage: "namespace of the LoadBalancer Service read by the service IP source"},
+	{name: "service-name", envVar: "SERVICE_NAME", usage: "name of the LoadBalancer Service read by the service IP source"},
+	{name: "annotation-key", envVar: "ANNOTATION_KEY", usage: "comma-separated node annotations holding the external IPs, tried in order"},
+	{name: "exclude-annotation", envVar: "EXCLUDE_ANNOTATION", usage: "node annotation that keeps a node out of DNS when set to true"},
+	{name: "exclude-taints", envVar: "EXCLUDE_TAINTS", usage: "comma-separated taint keys that keep a node out of DNS"},
+	{name: "exclude-notready", envVar: "EXCLUDE_NOTREADY", usage: "skip cordoned and NotReady nodes", isBool: true},
+	{name: "exclude-private", envVar: "EXCLUDE_PRIVATE", usage: "skip private, loopback and link-local addresses", isBool: true},
+	{name: "exclude-link-local", envVar: "EXCLUDE_LINK_LOCAL", usage: "skip link-local addresses such as fe80::/10 and 169.254.0.0/16", isBool: true},
+	{name: "include-cidrs", envVar: "INCLUDE_CIDRS", usage: "comma-separated CIDRs addresses must be in to be published"},
+	{name: "exclude-cidrs", envVar: "EXCLUDE_CIDRS", usage: "comma-separated CIDRs whose addresses are never published"},
+	{name: "ip-family", envVar: "IP_FAMILY", usage: "address family to publish: ipv4, ipv6 or all; the other family's records are left untouched"},
+	{name: "manage-a", envVar: "MANAGE_A", usage: "create, update and delete A records", isBool: true},
+	{name: "manage-aaaa", envVar: "MANAGE_AAAA", usage: "create, update and delete AAAA records", isBool: true},
+	{name: "manage-txt", envVar: "MANAGE_TXT", usage: "publish the TXT values of node annotations at the DNS records", isBool: true},
+	{name: "txt-annotation", envVar: "TXT_ANNOTATION", usage: "node annotation holding the TXT values for --manage-txt"},
+	{name: "manage-ptr", envVar: "MANAGE_PTR", usage: "manage PTR records for published IPs", isBool: true},
+	{name: "merge-records", envVar: "MERGE_RECORDS", usage: "keep record values that were not published by this controller", isBool: true},
+	{name: "no-delete", envVar: "NO_DELETE", usage: "never delete A/AAAA records or remove values from them", isBool: true},
+	{name: "shuffle-records", envVar: "SHUFFLE_RECORDS", usage: "randomize the order of A/AAAA values on every sync", isBool: true},
+	{name: "create-zone", envVar: "CREATE_ZONE", usage: "create the DNS zones at startup if they do not exist", isBool: true},
+	{name: "zone-kind", envVar: "ZONE_KIND", usage: "kind of created zones: Native or Master"},
+	{name: "zone-nameservers", envVar: "ZONE_NAMESERVERS", usage: "comma-separated NS names of created zones, required with --create-zone"},
+	{name: "notify-secondaries", envVar: "NOTIFY_SECONDARIES", usage: "send NOTIFYs for the zone after records changed", isBool: true},
+	{name: "rectify-zone", envVar: "RECTIFY_ZONE", usage: "rectify the zone after records changed", isBool: true},
+	{name: "leader-election", envVar: "LEADER_ELECTION", usage: "only sync while holding a leader election Lease", isBool: true},
+	{name: "lease-namespace", envVar: "LEASE_NAMESPACE", usage: "namespace of the leader election Lease"},
+	{name: "lease-name", envVar: "LEASE_NAME", usage: "name of the leader election Lease"},
+	{name: "watch", envVar: "WATCH_MODE", usage: "sync on node changes in addition to polling", isBool: true},
+	{name: "once", envVar: "RUN_ONCE", usage: "sync once and exit, e.g. when run as a CronJob", isBool: true},
+	{name: "dump", envVar: "DUMP", usage: "print the A/AAAA records a sync would publish as zone-file lines and exit, without contacting PowerDNS", isBool: true},
+	{name: "startup-retries", envVar: "STARTUP_RETRIES", usage: "retries of failed startup checks against Kubernetes and PowerDNS before exiting"},
+	{name: "startup-retry-interval", envVar: "STARTUP_RETRY_INTERVAL", usage: "wait between startup check attempts"},
+	{name: "selftest", envVar: "STARTUP_SELFTEST", usage: "write, read back and delete a TXT canary record at startup", isBool: true},
+	{name: "max-records", envVar: "MAX_RECORDS", usage: "maximum number of IPs to publish, preferring nodes with the highest k3s.io/dns-priority (0 for no limit)"},
+	{name: "max-ips-per-record", envVar: "MAX_IPS_PER_RECORD", usage: "maximum number of values of each A and AAAA record (0 for no limit)"},
+	{name: "ip-selection-strategy", envVar: "IP_SELECTION_STRATEGY", usage: "which IPs --max-ips-per-record keeps: stable, random or round-robin"},
+	{name: "min-records", envVar: "MIN_RECORDS", usage: "refuse to sync when fewer IPs are found (0 to disable)"},
+	{name: "max-delete-guard", envVar: "MAX_DELETE_GUARD", usage: "refuse to sync when more previously published IPs would be removed (0 to disable)"},
+	{name: "safety-guard-override", envVar: "SAFETY_GUARD_OVERRIDE", usage: "sync even when MIN_RECORDS or MAX_DELETE_GUARD trips", isBool: true},
+	{name: "dry-run", envVar: "DRY_RUN", usage: "log changes without sending them to PowerDNS", isBool: true},
+	{name: "webhook-url", envVar: "WEBHOOK_URL", usage: "URL to POST a JSON notification to after repeated sync failures and on recovery"},
+	{name: "sync-token", envVar: "SYNC_TOKEN", usage: "shared secret required in the X-Sync-Token header of POST /sync"},
+	{name: "webhook-failure-threshold", envVar: "WEBHOOK_FAILURE_THRESHOLD", usage: "consecutive sync failures before the webhook is notified"},
+	{name: "ready-failure-threshold", envVar: "READY_FAILURE_THRESHOLD", usage: "consecutive sync failures before /readyz reports not ready; 0 disables"},
+	{name: "metrics-addr", envVar: "METRICS_ADDR", usage: "listen address of the metrics endpoint"},
+	{name: "health-addr", envVar: "HEALTH_ADDR", usage: "listen address of the health endpoints"},
+	{name: "log-format", envVar: "LOG_FORMAT", usage: "log output format: text or json"},
+	{name: "log-level", envVar: "LOG_LEVEL", usage: "minimum log level: debug, info, warn or error"},
+}
+
+// errVersion is returned by parseFlags when build information was requested
+// with --version or the version subcommand.
+var errVersion = errors.New("version requested")
+
+// configOverrides holds values given on the command line, keyed by the
+// environment variable they take precedence over.
+var configOverrides = map[string]string{}
+
+// getEnv returns the command-line value for key if one was given, then the
+// environment variable, and finally the value from CONFIG_FILE.
+func getEnv(key string) string {
+	if value, ok := configOverrides[key]; ok {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fileValues[key]
+}
+
+// flagValue records a flag in the overrides map only when it is actually set,
+// so unset flags never mask environment variables.
+type flagValue struct {
+	envVar    string
+	isBool    bool
+	overrides map[string]string
+}
+
+func (f *flagValue) String() string { return "" }
+
+func (f *flagValue) Set(value string) error {
+	f.overrides[f.envVar] = value
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+// parseFlags parses command-line arguments into environment variable
+// overrides. It returns flag.ErrHelp when usage was requested.
+func parseFlags(args []string, output io.Writer) (map[string]string, error) {
+	overrides := make(map[string]string)
+
+	fs := flag.NewFlagSet("k8s-external-ip-powerdns", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags]\n       %s version\n\n", fs.Name(), fs.Name())
+		fmt.Fprintln(output, "Every flag overrides the environment variable shown in brackets.")
+		fmt.Fprintln(output)
+		fs.PrintDefaults()
+	}
+
+	for _, cf := range configFlags {
+		fs.Var(&flagValue{envVar: cf.envVar, isBool: cf.isBool, overrides: overrides}, cf.name, fmt.Sprintf("%s [%s]", cf.usage, cf.envVar))
+	}
+	showVersion := fs.Bool("version", false, "print build information and exit")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *showVersion || (fs.NArg() == 1 && fs.Arg(0) == "version") {
+		return nil, errVersion
+	}
+	if fs.NArg() > 0 {
+		err := fmt.Errorf("unexpected arguments: %v", fs.Args())
+		fmt.Fprintln(output, err)
+		fs.Usage()
+		return nil, err
+	}
+	return overrides, nil
+}
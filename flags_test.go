@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	overrides, err := parseFlags([]string{"--zone", "example.org", "--record=a.example.org,b.example.org", "--dry-run", "--ttl", "60s"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"DNS_ZONE":   "example.org",
+		"DNS_RECORD": "a.example.org,b.example.org",
+		"DRY_RUN":    "true",
+		"DNS_TTL":    "60s",
+	}
+	if len(overrides) != len(expected) {
+		t.Errorf("parseFlags() = %v, want %v", overrides, expected)
+	}
+	for key, value := range expected {
+		if overrides[key] != value {
+			t.Errorf("override %s = %q, want %q", key, overrides[key], value)
+		}
+	}
+}
+
+func TestParseFlagsErrors(t *testing.T) {
+	if _, err := parseFlags([]string{"--help"}, io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("parseFlags(--help) error = %v, want flag.ErrHelp", err)
+	}
+	if _, err := parseFlags([]string{"--no-such-flag"}, io.Discard); err == nil {
+		t.Error("parseFlags() with unknown flag should fail")
+	}
+	if _, err := parseFlags([]string{"extra"}, io.Discard); err == nil {
+		t.Error("parseFlags() with positional argument should fail")
+	}
+}
+
+func TestFlagsTakePrecedenceOverEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DNS_TTL", "5m")
+
+	overrides, err := parseFlags([]string{"--ttl", "1m", "--record", "flag.example.com"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	configOverrides = overrides
+	t.Cleanup(func() { configOverrides = map[string]string{} })
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.TTL != 60 {
+		t.Errorf("TTL = %d, want 60 from flag", config.TTL)
+	}
+	if len(config.DNSRecords) != 1 || config.DNSRecords[0] != "flag.example.com." {
+		t.Errorf("DNSRecords = %v, want [flag.example.com.] from flag", config.DNSRecords)
+	}
+	if config.DNSZone != "example.com." {
+		t.Errorf("DNSZone = %s, want example.com. from environment", config.DNSZone)
+	}
+}
+
+func TestParseFlagsVersion(t *testing.T) {
+	for _, args := range [][]string{{"--version"}, {"version"}, {"--zone", "example.org", "version"}} {
+		if _, err := parseFlags(args, io.Discard); !errors.Is(err, errVersion) {
+			t.Errorf("parseFlags(%v) error = %v, want errVersion", args, err)
+		}
+	}
+	if _, err := parseFlags([]string{"version", "extra"}, io.Discard); err == nil || errors.Is(err, errVersion) {
+		t.Errorf("parseFlags(version extra) error = %v, want unexpected arguments", err)
+	}
+}
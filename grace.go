@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// lastSeenIPs remembers, for REMOVAL_GRACE_PERIOD, every IP fetched and when
+// it was last seen, keyed on the canonical form of the address. It is kept
+// in memory only, so a restart forgets IPs that were being held. Like
+// lastPublishedIPs it is only used from the main goroutine.
+var lastSeenIPs map[string]seenIP
+
+// seenIP is an IP as last fetched, with the time it was.
+type seenIP struct {
+	ip   IPAddress
+	seen time.Time
+}
+
+// applyRemovalGrace returns ips together with the IPs that have disappeared
+// from them less than grace ago, so that a node briefly losing its
+// annotation, e.g. while kubelet restarts, does not make its IPs flap in DNS.
+// Held IPs keep the attribution they were last seen with. A grace of zero
+// disables holding.
+func applyRemovalGrace(ips []IPAddress, grace time.Duration, now time.Time) []IPAddress {
+	if grace <= 0 {
+		lastSeenIPs = nil
+		return ips
+	}
+	if lastSeenIPs == nil {
+		lastSeenIPs = make(map[string]seenIP)
+	}
+
+	fetched := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		key := ip.IP.String()
+		fetched[key] = true
+		lastSeenIPs[key] = seenIP{ip: ip, seen: now}
+	}
+
+	held := false
+	for key, last := range lastSeenIPs {
+		if fetched[key] {
+			continue
+		}
+		if gone := now.Sub(last.seen); gone >= grace {
+			slog.Info("Removal grace period elapsed, removing IP", "node", last.ip.Node, "ip", last.ip.String, "missing_for", gone.Round(time.Second))
+			delete(lastSeenIPs, key)
+			continue
+		}
+		slog.Info("Keeping missing IP during removal grace period", "node", last.ip.Node, "ip", last.ip.String, "remove_in", (grace - now.Sub(last.seen)).Round(time.Second))
+		ips = append(ips, last.ip)
+		held = true
+	}
+
+	if held {
+		sortIPAddresses(ips)
+	}
+	return ips
+}
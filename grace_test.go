@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyRemovalGrace(t *testing.T) {
+	t.Cleanup(func() { lastSeenIPs = nil })
+
+	fetch := func(value, node string) []IPAddress {
+		ips, _ := parseIPAddresses(value)
+		for i := range ips {
+			ips[i].Node = node
+		}
+		return ips
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	grace := 5 * time.Minute
+
+	steps := []struct {
+		after   time.Duration
+		fetched []IPAddress
+		want    []string
+	}{
+		{0, fetch("203.0.113.1,203.0.113.2,2001:db8::1", "node-a"), []string{"203.0.113.1", "203.0.113.2", "2001:db8::1"}},
+		// node-a briefly loses its annotation
+		{time.Minute, fetch("203.0.113.2", "node-b"), []string{"203.0.113.1", "203.0.113.2", "2001:db8::1"}},
+		// 203.0.113.1 comes back, which restarts its grace period
+		{4 * time.Minute, fetch("203.0.113.1,203.0.113.2", "node-a"), []string{"203.0.113.1", "203.0.113.2", "2001:db8::1"}},
+		{6 * time.Minute, fetch("203.0.113.2", "node-b"), []string{"203.0.113.1", "203.0.113.2"}},
+		{9 * time.Minute, fetch("203.0.113.2", "node-b"), []string{"203.0.113.2"}},
+	}
+	for _, step := range steps {
+		got := ipStrings(applyRemovalGrace(step.fetched, grace, start.Add(step.after)))
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("after %s: applyRemovalGrace() = %v, want %v", step.after, got, step.want)
+		}
+	}
+	if len(lastSeenIPs) != 1 {
+		t.Errorf("lastSeenIPs holds %d IPs, want only the one still fetched", len(lastSeenIPs))
+	}
+}
+
+func TestApplyRemovalGraceKeepsAttribution(t *testing.T) {
+	t.Cleanup(func() { lastSeenIPs = nil })
+
+	ips, _ := parseIPAddresses("203.0.113.1")
+	ips[0].Node = "node-a"
+	now := time.Now()
+	applyRemovalGrace(ips, time.Minute, now)
+
+	held := applyRemovalGrace(nil, time.Minute, now.Add(time.Second))
+	if len(held) != 1 || held[0].Node != "node-a" {
+		t.Errorf("applyRemovalGrace() = %+v, want 203.0.113.1 still attributed to node-a", held)
+	}
+}
+
+func TestApplyRemovalGraceDisabled(t *testing.T) {
+	t.Cleanup(func() { lastSeenIPs = nil })
+
+	ips, _ := parseIPAddresses("203.0.113.1")
+	now := time.Now()
+	applyRemovalGrace(ips, time.Minute, now)
+
+	if got := applyRemovalGrace(nil, 0, now.Add(time.Second)); len(got) != 0 {
+		t.Errorf("applyRemovalGrace() without grace = %v, want no IPs", got)
+	}
+	if lastSeenIPs != nil {
+		t.Errorf("lastSeenIPs = %v, want it cleared without grace", lastSeenIPs)
+	}
+}
+
+func TestLoadConfigRemovalGracePeriod(t *testing.T) {
+	setRequiredEnv(t)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.RemovalGrace != 0 {
+		t.Errorf("RemovalGrace = %v, want 0 by default", config.RemovalGrace)
+	}
+
+	t.Setenv("REMOVAL_GRACE_PERIOD", "5m")
+	if config, err = loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if config.RemovalGrace != 5*time.Minute {
+		t.Errorf("RemovalGrace = %v, want 5m", config.RemovalGrace)
+	}
+
+	t.Setenv("REMOVAL_GRACE_PERIOD", "-1m")
+	if config, err = loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if config.RemovalGrace != 0 {
+		t.Errorf("RemovalGrace = %v for a negative value, want 0", config.RemovalGrace)
+	}
+}
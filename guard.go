@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// lastPublishedIPs holds the IPs of the last successful sync. Syncs run on
+// the main goroutine only, so it needs no locking.
+var lastPublishedIPs []string
+
+// checkSafetyGuards refuses an update that would publish fewer than
+// MinRecords IPs, or remove more than MaxDeleteGuard of the previous IPs,
+// those currently published. Such drops usually come from a transient empty API
+// result rather than a real change, and would cause a DNS outage.
+func checkSafetyGuards(config *Config, previous []string, ips []IPAddress) error {
+	var reason string
+
+	if config.MinRecords > 0 && len(ips) < config.MinRecords {
+		reason = fmt.Sprintf("found %d IPs, fewer than MIN_RECORDS=%d", len(ips), config.MinRecords)
+	}
+
+	if reason == "" && config.MaxDeleteGuard > 0 {
+		current := make(map[string]bool, len(ips))
+		for _, ip := range canonicalIPStrings(ips) {
+			current[ip] = true
+		}
+		removed := 0
+		for _, ip := range previous {
+			if !current[canonicalValue(ip)] {
+				removed++
+			}
+		}
+		if removed > config.MaxDeleteGuard {
+			reason = fmt.Sprintf("%d of %d published IPs would be removed, more than MAX_DELETE_GUARD=%d", removed, len(previous), config.MaxDeleteGuard)
+		}
+	}
+
+	if reason == "" {
+		return nil
+	}
+	if config.GuardOverride {
+		slog.Warn("Safety guard tripped, continuing because SAFETY_GUARD_OVERRIDE is set", "reason", reason)
+		return nil
+	}
+	slog.Warn("Safety guard tripped, leaving DNS records unchanged", "reason", reason)
+	return fmt.Errorf("safety guard: %s", reason)
+}
+
+// canonicalIPStrings returns the addresses in the canonical form PowerDNS
+// returns, so that an annotation written as "2001:DB8::1" matches the
+// published "2001:db8::1".
+func canonicalIPStrings(ips []IPAddress) []string {
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.IP.String())
+	}
+	return values
+}
+
+// addressRecord is an A or AAAA record set the controller manages.
+type addressRecord struct {
+	name       string
+	recordType RecordType
+}
+
+// addressRecords returns the A and AAAA record sets the controller manages
+// in the zone of config: the DNS_RECORD names, and the DNS_RECORD_A and
+// DNS_RECORD_AAAA names where set.
+func addressRecords(config *Config) []addressRecord {
+	var records []addressRecord
+	shared := sharedRecordConfig(config)
+	for _, record := range config.DNSRecords {
+		if shared.ManageA {
+			records = append(records, addressRecord{validateDNSRecord(record), RecordTypeA})
+		}
+		if shared.ManageAAAA {
+			records = append(records, addressRecord{validateDNSRecord(record), RecordTypeAAAA})
+		}
+	}
+	if config.DNSRecordA != "" && config.ManageA {
+		records = append(records, addressRecord{config.DNSRecordA, RecordTypeA})
+	}
+	if config.DNSRecordAAAA != "" && config.ManageAAAA {
+		records = append(records, addressRecord{config.DNSRecordAAAA, RecordTypeAAAA})
+	}
+	return records
+}
+
+// publishedIPs returns the A and AAAA values currently published at the
+// managed records, read from PowerDNS so that MAX_DELETE_GUARD also holds
+// on the first sync after a restart. With MERGE_RECORDS, only the values
+// the controller published count. If no provider can be read, it falls back
+// to fallback, the IPs of the last successful sync.
+func publishedIPs(ctx context.Context, providers []DNSProvider, config *Config, fallback []string) []string {
+	published := make(map[string]bool)
+	failed := 0
+	for _, provider := range providers {
+		if err := readPublishedIPs(ctx, provider, config, published); err != nil {
+			slog.Warn("Failed to read published records for MAX_DELETE_GUARD", "target", provider.Name(), "error", err)
+			failed++
+		}
+	}
+	if failed == len(providers) {
+		return fallback
+	}
+
+	values := make([]string, 0, len(published))
+	for value := range published {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// readPublishedIPs adds the values published at provider to published.
+func readPublishedIPs(ctx context.Context, provider DNSProvider, config *Config, published map[string]bool) error {
+	for _, zoneConfig := range zoneConfigs(config) {
+		zone := validateDNSZone(zoneConfig.DNSZone)
+		for _, record := range addressRecords(zoneConfig) {
+			values, _, _, err := getRecordValues(ctx, provider, zoneConfig, zone, record.name, record.recordType)
+			if err != nil {
+				return err
+			}
+			if zoneConfig.MergeRecords {
+				managed, err := readManagedSet(ctx, provider, zoneConfig, zone, record.name)
+				if err != nil {
+					return err
+				}
+				values = intersectRecordValues(values, managed[record.recordType])
+			}
+			for _, value := range normalizeRecordValues(record.recordType, values) {
+				published[value] = true
+			}
+		}
+	}
+	return nil
+}
+
+// intersectRecordValues returns the values of a that are also in b.
+func intersectRecordValues(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, value := range b {
+		inB[value] = true
+	}
+	var both []string
+	for _, value := range a {
+		if inB[value] {
+			both = append(both, value)
+		}
+	}
+	return both
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckSafetyGuards(t *testing.T) {
+	ips, _ := parseIPAddresses("203.0.113.1,203.0.113.2")
+	previous := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"}
+	mixedCase, _ := parseIPAddresses("2001:DB8::1,2001:db8:0::2")
+
+	tests := []struct {
+		name     string
+		config   Config
+		previous []string
+		ips      []IPAddress
+		wantErr  bool
+	}{
+		{name: "guards disabled", config: Config{}, previous: previous, ips: nil},
+		{name: "enough records", config: Config{MinRecords: 2}, ips: ips},
+		{name: "too few records", config: Config{MinRecords: 3}, ips: ips, wantErr: true},
+		{name: "empty result on first sync", config: Config{MinRecords: 1}, ips: nil, wantErr: true},
+		{name: "removal within limit", config: Config{MaxDeleteGuard: 2}, previous: previous, ips: ips},
+		{name: "removal over limit", config: Config{MaxDeleteGuard: 1}, previous: previous, ips: ips, wantErr: true},
+		{name: "override", config: Config{MaxDeleteGuard: 1, GuardOverride: true}, previous: previous, ips: nil},
+		{name: "mixed-case IPv6", config: Config{MaxDeleteGuard: 1}, previous: []string{"2001:db8::1", "2001:db8::2"}, ips: mixedCase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSafetyGuards(&tt.config, tt.previous, tt.ips)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkSafetyGuards() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSafetyGuards(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("MIN_RECORDS", "2")
+	t.Setenv("MAX_DELETE_GUARD", "1")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.MinRecords != 2 || config.MaxDeleteGuard != 1 {
+		t.Errorf("MinRecords = %d, MaxDeleteGuard = %d, want 2 and 1", config.MinRecords, config.MaxDeleteGuard)
+	}
+
+	t.Setenv("MIN_RECORDS", "-1")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should reject a negative MIN_RECORDS")
+	}
+}
+
+func TestSyncDNSRecordsDeleteGuardAfterRestart(t *testing.T) {
+	t.Cleanup(func() { lastPublishedIPs = nil })
+
+	// A restarted controller has no previous sync, but PowerDNS still holds
+	// the records it published before
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeA, DefaultTTL, "203.0.113.1", "203.0.113.2", "203.0.113.3")
+	providers := []DNSProvider{mock.target(t)}
+	config := newUpdateTestConfig()
+	config.MaxDeleteGuard = 1
+
+	provider := newFakeIPProvider(t, "203.0.113.1", "node1", 0)
+	if err := syncDNSRecords(context.Background(), provider, providers, config); err == nil {
+		t.Fatal("syncDNSRecords() error = nil, want the delete guard to trip")
+	}
+	if got := mock.takeChanges(); len(got) != 0 {
+		t.Errorf("changes = %q, want none while the guard trips", got)
+	}
+
+	provider = newFakeIPProvider(t, "203.0.113.1,203.0.113.2", "node1", 0)
+	if err := syncDNSRecords(context.Background(), provider, providers, config); err != nil {
+		t.Fatalf("syncDNSRecords() error = %v, want one removal allowed", err)
+	}
+}
+
+func TestSyncDNSRecordsDeleteGuardMixedCase(t *testing.T) {
+	t.Cleanup(func() { lastPublishedIPs = nil })
+
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeAAAA, DefaultTTL, "2001:db8::1", "2001:db8::2")
+	providers := []DNSProvider{mock.target(t)}
+	config := newUpdateTestConfig()
+	config.MaxDeleteGuard = 1
+
+	// The annotation spells the published addresses differently, which
+	// removes nothing
+	provider := newFakeIPProvider(t, "2001:DB8::1,2001:db8:0::2", "node1", 0)
+	if err := syncDNSRecords(context.Background(), provider, providers, config); err != nil {
+		t.Fatalf("syncDNSRecords() error = %v, want no removal counted", err)
+	}
+	if got := strings.Join(lastPublishedIPs, ","); got != "2001:db8::1,2001:db8::2" {
+		t.Errorf("lastPublishedIPs = %s, want the canonical addresses", got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHealthAddr is the listen address of the health endpoints.
+	DefaultHealthAddr = ":8080"
+	// ReadyStaleFactor is how many sync intervals may pass without a
+	// successful sync before the service reports itself as not ready.
+	ReadyStaleFactor = 3
+)
+
+// SyncState tracks the progress of the sync loop for health reporting.
+type SyncState struct {
+	mu sync.RWMutex
+
+	running         bool
+	standby         bool // Waiting for the leader election Lease
+	lastSuccessTime time.Time
+	lastSyncTime    time.Time
+	lastError       string
+	failures        int // Consecutive failed syncs
+	ips             []string
+	sources         map[string][]string
+}
+
+var syncState = &SyncState{}
+
+// setRunning marks the main loop as started.
+func (s *SyncState) setRunning() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = true
+}
+
+// setStandby marks whether the replica is a leader election standby, which
+// is ready without syncing.
+func (s *SyncState) setStandby(standby bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.standby = standby
+}
+
+// recordSync updates the state after a sync attempt.
+func (s *SyncState) recordSync(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSyncTime = time.Now()
+	if err != nil {
+		s.lastError = err.Error()
+		s.failures++
+		return
+	}
+	s.lastError = ""
+	s.failures = 0
+	s.lastSuccessTime = s.lastSyncTime
+}
+
+// recordFetch stores the most recently fetched IPs and which node or
+// service each one came from.
+func (s *SyncState) recordFetch(ips []string, sources map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ips = ips
+	s.sources = sources
+}
+
+// healthzHandler reports healthy once the main loop is running.
+func (s *SyncState) healthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		running := s.running
+		s.mu.RUnlock()
+
+		if !running {
+			http.Error(w, "sync loop not running", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// readyzHandler reports ready once a sync has succeeded and the last
+// successful sync is not older than ReadyStaleFactor sync intervals. With a
+// failureThreshold above zero, it also reports not ready once that many
+// syncs in a row have failed, however recent the last success. A leader
+// election standby is always ready, so that rolling updates are not held up
+// by replicas that never sync.
+func (s *SyncState) readyzHandler(syncInterval time.Duration, failureThreshold int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		standby := s.standby
+		lastSuccess := s.lastSuccessTime
+		failures := s.failures
+		s.mu.RUnlock()
+
+		if standby {
+			fmt.Fprintln(w, "ok (standby)")
+			return
+		}
+
+		if lastSuccess.IsZero() {
+			http.Error(w, "initial sync has not completed", http.StatusServiceUnavailable)
+			return
+		}
+
+		if failureThreshold > 0 && failures >= failureThreshold {
+			http.Error(w, fmt.Sprintf("last %d syncs failed", failures), http.StatusServiceUnavailable)
+			return
+		}
+
+		if age := time.Since(lastSuccess); age > ReadyStaleFactor*syncInterval {
+			http.Error(w, fmt.Sprintf("last successful sync was %v ago", age.Round(time.Second)), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// syncStatus is the JSON body of the /status endpoint.
+type syncStatus struct {
+	IPs             []string            `json:"ips"`
+	Sources         map[string][]string `json:"sources"`
+	LastSyncTime    *time.Time          `json:"last_sync_time,omitempty"`
+	LastSuccessTime *time.Time          `json:"last_success_time,omitempty"`
+	LastError       string              `json:"last_error,omitempty"`
+	Failures        int                 `json:"consecutive_failures"`
+}
+
+// statusHandler reports the last fetched IPs, their sources and the
+// outcome of the last sync as JSON, for debugging.
+func (s *SyncState) statusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		status := syncStatus{
+			IPs:       append([]string{}, s.ips...),
+			Sources:   make(map[string][]string, len(s.sources)),
+			LastError: s.lastError,
+			Failures:  s.failures,
+		}
+		for source, ips := range s.sources {
+			status.Sources[source] = append([]string{}, ips...)
+		}
+		if !s.lastSyncTime.IsZero() {
+			t := s.lastSyncTime
+			status.LastSyncTime = &t
+		}
+		if !s.lastSuccessTime.IsZero() {
+			t := s.lastSuccessTime
+			status.LastSuccessTime = &t
+		}
+		s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	state := &SyncState{}
+	healthz := state.healthzHandler()
+	readyz := state.readyzHandler(30*time.Second, 0)
+
+	status := func(handler http.Handler) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+		return recorder.Code
+	}
+
+	if code := status(healthz); code != http.StatusServiceUnavailable {
+		t.Errorf("healthz before loop start = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if code := status(readyz); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before initial sync = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	state.recordSync(errors.New("boom"))
+	if code := status(readyz); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz after failed sync = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	state.recordSync(nil)
+	state.setRunning()
+	if code := status(healthz); code != http.StatusOK {
+		t.Errorf("healthz while running = %d, want %d", code, http.StatusOK)
+	}
+	if code := status(readyz); code != http.StatusOK {
+		t.Errorf("readyz after successful sync = %d, want %d", code, http.StatusOK)
+	}
+
+	state.lastSuccessTime = time.Now().Add(-2 * time.Minute)
+	if code := status(readyz); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz with stale sync = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestReadyzFailureThreshold(t *testing.T) {
+	state := &SyncState{}
+	readyz := state.readyzHandler(30*time.Second, 3)
+
+	status := func() int {
+		recorder := httptest.NewRecorder()
+		readyz.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+		return recorder.Code
+	}
+
+	state.recordSync(nil)
+	for i := 1; i < 3; i++ {
+		state.recordSync(errors.New("boom"))
+		if code := status(); code != http.StatusOK {
+			t.Errorf("readyz after %d failures = %d, want %d", i, code, http.StatusOK)
+		}
+	}
+
+	state.recordSync(errors.New("boom"))
+	if code := status(); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz after 3 failures = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	state.recordSync(nil)
+	if code := status(); code != http.StatusOK {
+		t.Errorf("readyz after recovery = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestReadyzStandby(t *testing.T) {
+	state := &SyncState{}
+	readyz := state.readyzHandler(30*time.Second, 0)
+
+	status := func() int {
+		recorder := httptest.NewRecorder()
+		readyz.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+		return recorder.Code
+	}
+
+	state.setStandby(true)
+	if code := status(); code != http.StatusOK {
+		t.Errorf("readyz while standing by = %d, want %d", code, http.StatusOK)
+	}
+
+	// A new leader is only ready once its own first sync has succeeded
+	state.setStandby(false)
+	if code := status(); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz after acquiring leadership = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	state.recordSync(nil)
+	if code := status(); code != http.StatusOK {
+		t.Errorf("readyz after the leader's sync = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	state := &SyncState{}
+	state.recordFetch([]string{"203.0.113.1", "2001:db8::1"}, map[string][]string{"node1": {"203.0.113.1", "2001:db8::1"}})
+	state.recordSync(errors.New("powerdns unavailable"))
+
+	recorder := httptest.NewRecorder()
+	state.statusHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/status", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var status syncStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(status.IPs) != 2 || len(status.Sources["node1"]) != 2 {
+		t.Errorf("status IPs = %v, sources = %v", status.IPs, status.Sources)
+	}
+	if status.LastError != "powerdns unavailable" {
+		t.Errorf("last_error = %q, want powerdns unavailable", status.LastError)
+	}
+	if status.Failures != 1 {
+		t.Errorf("consecutive_failures = %d, want 1", status.Failures)
+	}
+	if status.LastSyncTime == nil || status.LastSuccessTime != nil {
+		t.Errorf("last_sync_time = %v, last_success_time = %v, want only a sync time", status.LastSyncTime, status.LastSuccessTime)
+	}
+}
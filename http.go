@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// httpServers groups handlers by listen address so that endpoints configured
+// with the same address share a single server.
+type httpServers struct {
+	muxes   map[string]*http.ServeMux
+	order   []string
+	servers []*http.Server
+}
+
+func newHTTPServers() *httpServers {
+	return &httpServers{muxes: make(map[string]*http.ServeMux)}
+}
+
+// Handle registers handler for pattern on the server listening on addr.
+func (s *httpServers) Handle(addr, pattern string, handler http.Handler) {
+	mux, ok := s.muxes[addr]
+	if !ok {
+		mux = http.NewServeMux()
+		s.muxes[addr] = mux
+		s.order = append(s.order, addr)
+	}
+	mux.Handle(pattern, handler)
+}
+
+// Start begins serving every configured address in the background.
+func (s *httpServers) Start() {
+	for _, addr := range s.order {
+		server := &http.Server{Addr: addr, Handler: s.muxes[addr]}
+		s.servers = append(s.servers, server)
+
+		go func() {
+			slog.Info("Serving HTTP endpoints", "addr", server.Addr)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP server stopped", "addr", server.Addr, "error", err)
+			}
+		}()
+	}
+}
+
+// Shutdown gracefully stops all started servers.
+func (s *httpServers) Shutdown(ctx context.Context) {
+	for _, server := range s.servers {
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Warn("Failed to shut down HTTP server", "addr", server.Addr, "error", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// IPProvider is a source of IPs to publish. Each IP is attributed, through
+// its Node field, to the node, service or static list it was read from.
+type IPProvider interface {
+	GetIPs(ctx context.Context) ([]IPAddress, error)
+}
+
+// newIPProvider returns the provider for the sources selected by IP_SOURCE,
+// followed by the static EXTRA_IPS, merged and capped at MAX_RECORDS.
+func newIPProvider(clientset kubernetes.Interface, config *Config) IPProvider {
+	var providers []IPProvider
+	if hasIPSource(config.IPSource, IPSourceAnnotation) || hasIPSource(config.IPSource, IPSourceStatus) {
+		providers = append(providers, &nodeIPProvider{clientset: clientset, config: config})
+	}
+	if hasIPSource(config.IPSource, IPSourceService) {
+		providers = append(providers, &serviceIPProvider{clientset: clientset, config: config})
+	}
+	providers = append(providers, staticIPProvider{ips: config.ExtraIPs, family: config.IPFamily})
+	return &mergedIPProvider{providers: providers, maxRecords: config.MaxRecords}
+}
+
+// nodeIPProvider reads the IPs of the matching nodes, from their annotation
+// or status depending on IP_SOURCE.
+type nodeIPProvider struct {
+	clientset kubernetes.Interface
+	config    *Config
+}
+
+func (p *nodeIPProvider) GetIPs(ctx context.Context) ([]IPAddress, error) {
+	return fetchNodeIPs(ctx, p.clientset, p.config)
+}
+
+// serviceIPProvider reads the load balancer ingress IPs of the configured
+// Service.
+type serviceIPProvider struct {
+	clientset kubernetes.Interface
+	config    *Config
+}
+
+func (p *serviceIPProvider) GetIPs(ctx context.Context) ([]IPAddress, error) {
+	serviceIPs, err := fetchServiceIPs(ctx, p.clientset, p.config)
+	if err != nil {
+		return nil, err
+	}
+	source := "service/" + p.config.ServiceNamespace + "/" + p.config.ServiceName
+	return appendUniqueIPs(nil, make(map[string]string), filterIPAddresses(serviceIPs, p.config), source, 0), nil
+}
+
+// staticIPProvider returns the EXTRA_IPS of the published IP_FAMILY. They
+// outrank every node, so that they win over a node or service reporting the
+// same IP and MAX_RECORDS never drops them.
+type staticIPProvider struct {
+	ips    []IPAddress
+	family string
+}
+
+func (p staticIPProvider) GetIPs(ctx context.Context) ([]IPAddress, error) {
+	var ips []IPAddress
+	for _, ip := range p.ips {
+		if !inIPFamily(p.family, ip) {
+			slog.Debug("Excluding address", "ip", ip.String, "reason", "not in IP_FAMILY "+p.family)
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	return appendUniqueIPs(nil, make(map[string]string), ips, ExtraIPsSource, math.MaxInt), nil
+}
+
+// mergedIPProvider combines providers in order. An IP returned by several
+// providers is kept once, attributed to the one with the highest priority,
+// or the first on a tie; such overlaps are expected, as service load
+// balancers such as ServiceLB reuse node IPs, and are not reported as
+// duplicates. The result is capped at maxRecords IPs, when set, keeping
+// those of the highest priority.
+type mergedIPProvider struct {
+	providers  []IPProvider
+	maxRecords int
+}
+
+func (p *mergedIPProvider) GetIPs(ctx context.Context) ([]IPAddress, error) {
+	var merged []IPAddress
+	seen := make(map[string]int) // Index in merged of each IP
+	for _, provider := range p.providers {
+		ips, err := provider.GetIPs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			key := ip.IP.String()
+			if i, ok := seen[key]; ok {
+				if ip.Priority > merged[i].Priority {
+					merged[i] = ip
+				}
+				continue
+			}
+			seen[key] = len(merged)
+			merged = append(merged, ip)
+		}
+	}
+
+	if p.maxRecords > 0 && len(merged) > p.maxRecords {
+		var dropped []IPAddress
+		merged, dropped = selectByPriority(merged, p.maxRecords)
+		for _, ip := range dropped {
+			slog.Info("Dropping IP due to MAX_RECORDS cap", "node", ip.Node, "ip", ip.String, "priority", ip.Priority)
+		}
+	}
+
+	sortIPAddresses(merged)
+	return merged, nil
+}
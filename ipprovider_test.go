@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeIPProvider returns fixed IPs, or a fixed error.
+type fakeIPProvider struct {
+	ips []IPAddress
+	err error
+}
+
+func (p *fakeIPProvider) GetIPs(ctx context.Context) ([]IPAddress, error) {
+	return p.ips, p.err
+}
+
+// newFakeIPProvider returns a fakeIPProvider for the comma-separated IPs,
+// attributed to source with the given priority.
+func newFakeIPProvider(t *testing.T, ips, source string, priority int) *fakeIPProvider {
+	t.Helper()
+	parsed, err := parseIPAddresses(ips)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range parsed {
+		parsed[i].Node = source
+		parsed[i].Priority = priority
+	}
+	return &fakeIPProvider{ips: parsed}
+}
+
+func attributedIPs(ips []IPAddress) []string {
+	var attributed []string
+	for _, ip := range ips {
+		attributed = append(attributed, ip.String+"@"+ip.Node)
+	}
+	return attributed
+}
+
+func TestMergedIPProvider(t *testing.T) {
+	oldMetrics := metrics
+	metrics = newMetrics()
+	defer func() { metrics = oldMetrics }()
+
+	provider := &mergedIPProvider{providers: []IPProvider{
+		newFakeIPProvider(t, "203.0.113.5,2001:db8::5", "node1", 0),
+		newFakeIPProvider(t, "203.0.113.5,203.0.113.9", "service/default/traefik", 0),
+		staticIPProvider{ips: newFakeIPProvider(t, "2001:db8:0:0:0:0:0:5,198.51.100.10", "", 0).ips, family: IPFamilyAll},
+	}}
+
+	ips, err := provider.GetIPs(context.Background())
+	if err != nil {
+		t.Fatalf("GetIPs() error = %v", err)
+	}
+	// The static entry wins over the node reporting the same IP
+	want := []string{"198.51.100.10@static", "203.0.113.5@node1", "203.0.113.9@service/default/traefik", "2001:db8:0:0:0:0:0:5@static"}
+	if got := attributedIPs(ips); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIPs() = %v, want %v", got, want)
+	}
+	if metrics.duplicateIPs != 0 {
+		t.Errorf("duplicateIPs = %d, want overlaps between providers not counted", metrics.duplicateIPs)
+	}
+}
+
+func TestMergedIPProviderMaxRecords(t *testing.T) {
+	provider := &mergedIPProvider{
+		providers: []IPProvider{
+			newFakeIPProvider(t, "203.0.113.1,203.0.113.2", "low", 0),
+			newFakeIPProvider(t, "203.0.113.3", "high", 10),
+			staticIPProvider{ips: newFakeIPProvider(t, "198.51.100.10", "", 0).ips},
+		},
+		maxRecords: 2,
+	}
+
+	ips, err := provider.GetIPs(context.Background())
+	if err != nil {
+		t.Fatalf("GetIPs() error = %v", err)
+	}
+	want := []string{"198.51.100.10@static", "203.0.113.3@high"}
+	if got := attributedIPs(ips); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIPs() = %v, want %v", got, want)
+	}
+}
+
+func TestStaticIPProviderFamily(t *testing.T) {
+	extra := newFakeIPProvider(t, "198.51.100.10,2001:db8::10", "", 0).ips
+	for family, want := range map[string][]string{
+		IPFamilyAll:  {"198.51.100.10@static", "2001:db8::10@static"},
+		IPFamilyIPv4: {"198.51.100.10@static"},
+		IPFamilyIPv6: {"2001:db8::10@static"},
+	} {
+		ips, err := staticIPProvider{ips: extra, family: family}.GetIPs(context.Background())
+		if err != nil {
+			t.Fatalf("GetIPs() error = %v", err)
+		}
+		if got := attributedIPs(ips); !reflect.DeepEqual(got, want) {
+			t.Errorf("GetIPs() with IP_FAMILY %s = %v, want %v", family, got, want)
+		}
+	}
+}
+
+func TestMergedIPProviderError(t *testing.T) {
+	failure := errors.New("boom")
+	provider := &mergedIPProvider{providers: []IPProvider{
+		newFakeIPProvider(t, "203.0.113.1", "node1", 0),
+		&fakeIPProvider{err: failure},
+	}}
+
+	if _, err := provider.GetIPs(context.Background()); !errors.Is(err, failure) {
+		t.Errorf("GetIPs() error = %v, want %v", err, failure)
+	}
+}
+
+func TestNewIPProviderServiceOverlap(t *testing.T) {
+	oldMetrics := metrics
+	metrics = newMetrics()
+	defer func() { metrics = oldMetrics }()
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "traefik", Namespace: "kube-system"},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{
+			{IP: "203.0.113.1"},
+			{IP: "203.0.113.7"},
+		}}},
+	}
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "203.0.113.1"}, nil),
+		service,
+	)
+	config := newFetchTestConfig()
+	config.IPSource = IPSourceAnnotation + "," + IPSourceService
+	config.ServiceNamespace = "kube-system"
+	config.ServiceName = "traefik"
+
+	ips, err := newIPProvider(clientset, config).GetIPs(context.Background())
+	if err != nil {
+		t.Fatalf("GetIPs() error = %v", err)
+	}
+	want := []string{"203.0.113.1@node-a", "203.0.113.7@service/kube-system/traefik"}
+	if got := attributedIPs(ips); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIPs() = %v, want %v", got, want)
+	}
+	if metrics.duplicateIPs != 0 {
+		t.Errorf("duplicateIPs = %d, want the service overlap not counted", metrics.duplicateIPs)
+	}
+}
+
+func TestSyncDNSRecordsFakeProvider(t *testing.T) {
+	t.Cleanup(func() { lastPublishedIPs = nil })
+
+	mock := newMockPowerDNS(t, "example.com.")
+	providers := []DNSProvider{mock.target(t)}
+	provider := newFakeIPProvider(t, "203.0.113.1,2001:db8::1", "node1", 0)
+
+	if err := syncDNSRecords(context.Background(), provider, providers, newUpdateTestConfig()); err != nil {
+		t.Fatalf("syncDNSRecords() error = %v", err)
+	}
+	if got := mock.values("www.example.com.", RecordTypeA); !reflect.DeepEqual(got, []string{"203.0.113.1"}) {
+		t.Errorf("A record = %v, want [203.0.113.1]", got)
+	}
+	if got := mock.values("www.example.com.", RecordTypeAAAA); !reflect.DeepEqual(got, []string{"2001:db8::1"}) {
+		t.Errorf("AAAA record = %v, want [2001:db8::1]", got)
+	}
+
+	provider.err = errors.New("boom")
+	if err := syncDNSRecords(context.Background(), provider, providers, newUpdateTestConfig()); err == nil {
+		t.Error("syncDNSRecords() should fail when the IP provider fails")
+	}
+}
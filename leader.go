@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// DefaultLeaseName is the Lease used for leader election when LEASE_NAME is unset.
+const DefaultLeaseName = "k8s-external-ip-powerdns"
+
+// Leader election timings, the usual client-go controller defaults.
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// runWithLeaderElection blocks until ctx is cancelled or leadership is lost,
+// calling run with a context that is cancelled when leadership ends. Only
+// the leader syncs; other replicas stand by until the Lease expires. The
+// Lease is released on shutdown so a standby replica takes over right away.
+// In run-once mode it returns, releasing the Lease, as soon as run does.
+func runWithLeaderElection(ctx context.Context, clientset kubernetes.Interface, config *Config, run func(ctx context.Context)) {
+	identity := commentInstance()
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: config.LeaseNamespace,
+			Name:      config.LeaseName,
+		},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	// Standby replicas are healthy and ready, they are just not syncing
+	syncState.setRunning()
+	syncState.setStandby(true)
+
+	// Cancelling the election, on shutdown or once a run-once sync is done,
+	// releases the Lease and makes RunOrDie return
+	electionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slog.Info("Waiting for leadership", "lease", config.LeaseNamespace+"/"+config.LeaseName, "identity", identity)
+	leaderelection.RunOrDie(electionCtx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            config.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				slog.Info("Acquired leadership, starting sync", "identity", identity)
+				syncState.setStandby(false)
+				run(ctx)
+				if config.RunOnce {
+					cancel()
+				}
+			},
+			OnStoppedLeading: func() {
+				if electionCtx.Err() != nil {
+					slog.Info("Released leadership")
+					return
+				}
+				// Another replica may already be writing, so stop rather than
+				// risk competing updates; the restart rejoins as a standby
+				fatal("Lost leadership, exiting", "identity", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					slog.Info("Standing by, another replica is the leader", "leader", leader)
+				}
+			},
+		},
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadConfigLeaderElection(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("LEADER_ELECTION", "true")
+	t.Setenv("POD_NAMESPACE", "tools")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if !config.LeaderElection || config.LeaseNamespace != "tools" || config.LeaseName != DefaultLeaseName {
+		t.Errorf("leader election config = %v %s/%s, want true tools/%s", config.LeaderElection, config.LeaseNamespace, config.LeaseName, DefaultLeaseName)
+	}
+
+	t.Setenv("LEASE_NAMESPACE", "dns")
+	t.Setenv("LEASE_NAME", "sync")
+	config, err = loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.LeaseNamespace != "dns" || config.LeaseName != "sync" {
+		t.Errorf("lease = %s/%s, want dns/sync", config.LeaseNamespace, config.LeaseName)
+	}
+}
+
+func TestRunWithLeaderElection(t *testing.T) {
+	t.Setenv("POD_NAME", "replica-1")
+	clientset := fake.NewSimpleClientset()
+	config := &Config{LeaseNamespace: "tools", LeaseName: "dns-sync"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	led := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runWithLeaderElection(ctx, clientset, config, func(leaderCtx context.Context) {
+			close(led)
+			<-leaderCtx.Done()
+		})
+	}()
+
+	select {
+	case <-led:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runWithLeaderElection() never started leading")
+	}
+
+	lease, err := clientset.CoordinationV1().Leases("tools").Get(context.Background(), "dns-sync", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting lease: %v", err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != "replica-1" {
+		t.Errorf("lease holder = %v, want replica-1", lease.Spec.HolderIdentity)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runWithLeaderElection() did not return after cancellation")
+	}
+}
+
+func TestRunWithLeaderElectionRunOnce(t *testing.T) {
+	t.Setenv("POD_NAME", "replica-1")
+	clientset := fake.NewSimpleClientset()
+	config := &Config{LeaseNamespace: "tools", LeaseName: "dns-sync", RunOnce: true}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runWithLeaderElection(context.Background(), clientset, config, func(context.Context) {})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runWithLeaderElection() kept holding the lease after the run-once sync")
+	}
+
+	lease, err := clientset.CoordinationV1().Leases("tools").Get(context.Background(), "dns-sync", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting lease: %v", err)
+	}
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		t.Errorf("lease holder = %s, want the lease released", *lease.Spec.HolderIdentity)
+	}
+}
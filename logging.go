@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Log output formats, selected with LOG_FORMAT.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// parseLogLevel converts a LOG_LEVEL value to a slog level, defaulting to info.
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid LOG_LEVEL %q: must be debug, info, warn or error", level)
+	}
+}
+
+// configureLogging installs the default slog logger for the given format and
+// level. The text format keeps the standard log package output, so existing
+// log lines only gain a level and trailing key=value attributes.
+func configureLogging(format, level string) error {
+	logLevel, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(format) {
+	case "", LogFormatText:
+		// slog's default handler writes through the log package
+		slog.SetLogLoggerLevel(logLevel)
+	case LogFormatJSON:
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q: must be %s or %s", format, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// fatal logs msg at error level and exits with ExitFailure, like log.Fatal.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(ExitFailure)
+}
+
+// ipStrings returns the textual form of each address, for log attributes.
+func ipStrings(ips []IPAddress) []string {
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.String)
+	}
+	return values
+}
+
+// ipSources groups addresses by the node or service they were read from.
+func ipSources(ips []IPAddress) map[string][]string {
+	sources := make(map[string][]string)
+	for _, ip := range ips {
+		sources[ip.Node] = append(sources[ip.Node], ip.String)
+	}
+	return sources
+}
+
+// nodeAttribution formats which node contributed which addresses, e.g.
+// "node1=203.0.113.1,2001:db8::1 node2=203.0.113.2", for log attributes.
+func nodeAttribution(ips []IPAddress) string {
+	sources := ipSources(ips)
+	nodes := make([]string, 0, len(sources))
+	for node := range sources {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		parts = append(parts, node+"="+strings.Join(sources[node], ","))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	tests := []struct {
+		format      string
+		expectError bool
+	}{
+		{format: "", expectError: false},
+		{format: "text", expectError: false},
+		{format: "JSON", expectError: false},
+		{format: "logfmt", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			err := configureLogging(tt.format, "")
+			if (err != nil) != tt.expectError {
+				t.Errorf("configureLogging(%q) error = %v, expectError %v", tt.format, err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level       string
+		expected    slog.Level
+		expectError bool
+	}{
+		{level: "", expected: slog.LevelInfo},
+		{level: "debug", expected: slog.LevelDebug},
+		{level: "INFO", expected: slog.LevelInfo},
+		{level: "warn", expected: slog.LevelWarn},
+		{level: "error", expected: slog.LevelError},
+		{level: "verbose", expected: slog.LevelInfo, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			level, err := parseLogLevel(tt.level)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseLogLevel(%q) error = %v, expectError %v", tt.level, err, tt.expectError)
+			}
+			if level != tt.expected {
+				t.Errorf("parseLogLevel(%q) = %v, want %v", tt.level, level, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNodeAttribution(t *testing.T) {
+	ips := []IPAddress{
+		{String: "203.0.113.2", Node: "node2"},
+		{String: "203.0.113.1", Node: "node1"},
+		{String: "2001:db8::1", Node: "node1"},
+	}
+
+	want := "node1=203.0.113.1,2001:db8::1 node2=203.0.113.2"
+	if got := nodeAttribution(ips); got != want {
+		t.Errorf("nodeAttribution() = %q, want %q", got, want)
+	}
+}
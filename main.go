@@ -1,18 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
 	"net"
-	"net/http"
 	"os"
+	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/joeig/go-powerdns/v3"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -25,47 +35,176 @@ var (
 	buildDate = "unknown"
 )
 
+// Sources external IPs can be read from, selected with IP_SOURCE.
+const (
+	IPSourceAnnotation = "annotation"
+	IPSourceStatus     = "status"
+	IPSourceBoth       = "both"
+	IPSourceService    = "service"
+)
+
+// IP families that can be published, selected with IP_FAMILY.
+const (
+	IPFamilyIPv4 = "ipv4"
+	IPFamilyIPv6 = "ipv6"
+	IPFamilyAll  = "all"
+)
+
+// Kubernetes client config sources, forced with K8S_MODE. Without it the
+// in-cluster config is tried first, falling back to a kubeconfig file.
+const (
+	K8sModeInCluster  = "incluster"
+	K8sModeKubeconfig = "kubeconfig"
+)
+
 const (
 	ExternalIPAnnotation = "k3s.io/external-ip"
+	DefaultSeparator     = ","
+	PriorityAnnotation   = "k3s.io/dns-priority"
+	TTLAnnotation        = "k3s.io/dns-ttl"
+	ExcludeAnnotation    = "k3s.io/dns-exclude"
 	DefaultSyncInterval  = 30 * time.Second
-	DefaultTTL          = 300
+	DefaultTTL           = 300
+	MinTTL               = 1
+	MaxTTL               = 604800 // One week
+	ShutdownTimeout      = 10 * time.Second
+	DefaultK8sTimeout    = 15 * time.Second
+	ExtraIPsSource       = "static"
+	// DefaultFailureRetryInterval is the first retry delay after a failed sync
+	DefaultFailureRetryInterval = 5 * time.Second
 )
 
 type Config struct {
-	PowerDNSURL     string
-	PowerDNSAPIKey  string
-	PowerDNSVHost   string
-	DNSZone         string
-	DNSRecord       string
-	SyncInterval    time.Duration
-	KubeConfig      string
-	TTL             int
-	NodeSelector    string // Label selector for nodes to include in DNS updates
+	PowerDNSURLs       []string
+	PowerDNSAPIKey     string
+	PowerDNSServerID   string
+	DNSZone            string
+	DNSRecords         []string
+	SyncInterval       time.Duration
+	KubeConfig         string
+	TTL                int
+	NodeSelector       string        // Label selector for nodes to include in DNS updates
+	NodesFile          string        // JSON node list read instead of the Kubernetes API, for development
+	DryRun             bool          // Log intended changes without calling the PowerDNS API
+	WatchMode          bool          // React to node changes via the watch API in addition to polling
+	MetricsAddr        string        // Listen address of the Prometheus metrics endpoint
+	HealthAddr         string        // Listen address of the liveness and readiness endpoints
+	PowerDNSMaxRetries int           // Retries for transient PowerDNS API failures
+	ExcludeNotReady    bool          // Skip nodes that are unschedulable or not Ready
+	IPSource           string        // Comma-separated IP sources: annotation, status, both and/or service
+	AnnotationKeys     []string      // Node annotations holding the external IPs, in order of preference
+	ManagePTR          bool          // Point PTR records of published IPs at the first DNS record
+	TTLA               int           // TTL override for A records, 0 to use TTL
+	TTLAAAA            int           // TTL override for AAAA records, 0 to use TTL
+	ExcludePrivate     bool          // Drop private, loopback and link-local addresses
+	ExcludeLinkLocal   bool          // Drop link-local addresses, which are only valid on their own link
+	IncludeCIDRs       []*net.IPNet  // Only publish addresses within these ranges, if any
+	ExcludeCIDRs       []*net.IPNet  // Never publish addresses within these ranges
+	RunOnce            bool          // Sync once and exit instead of running the loop
+	Dump               bool          // Print the desired records as zone-file lines and exit
+	StartupSelfTest    bool          // Write, read back and delete a TXT canary at startup
+	MaxRecords         int           // Cap on published IPs, highest node priority first; 0 for no cap
+	CNAME              string        // Alias FQDN pointed at the first DNS record, if set
+	DNSRecordA         string        // Name receiving the A records instead of DNSRecords, if set
+	DNSRecordAAAA      string        // Name receiving the AAAA records instead of DNSRecords, if set
+	ExcludeAnnotation  string        // Node annotation that, when true, keeps the node out of DNS
+	ExcludeTaints      []string      // Taint keys that keep a node out of DNS, such as the control-plane taint
+	SyncJitter         float64       // Max random extra delay per sync, as a fraction of SyncInterval
+	K8sTimeout         time.Duration // Timeout for Kubernetes API calls
+	ServiceNamespace   string        // Namespace of the LoadBalancer Service for IP_SOURCE=service
+	ServiceName        string        // Name of the LoadBalancer Service for IP_SOURCE=service
+	TXTOwnerID         string        // Instance id written to ownership TXT records; empty disables ownership
+	ManageA            bool          // Create, update and delete A records
+	ManageAAAA         bool          // Create, update and delete AAAA records
+	MinRecords         int           // Refuse to sync when fewer IPs are found; 0 disables
+	MaxDeleteGuard     int           // Refuse to sync when more previously published IPs would go; 0 disables
+	GuardOverride      bool          // Sync even when a safety guard trips
+	PowerDNSTimeout    time.Duration // Timeout of each PowerDNS API request
+	PowerDNSClientCert string        // PEM client certificate for mTLS to PowerDNS
+	PowerDNSClientKey  string        // PEM private key of the client certificate
+	PowerDNSCACert     string        // PEM CA bundle used to verify PowerDNS
+	PowerDNSInsecure   bool          // Skip verification of the PowerDNS server certificate
+	WebhookURL         string        // URL notified about repeated sync failures and recovery
+	SyncToken          string        // Shared secret required by POST /sync, if set
+	WebhookThreshold   int           // Consecutive failures before the webhook is notified
+	ReadyFailures      int           // Consecutive failures before /readyz reports not ready, 0 to disable
+	MinWriteInterval   time.Duration // Quiet period after node changes before the triggered sync, 0 to sync at once
+	RemovalGrace       time.Duration // How long a disappeared IP stays published, 0 to remove it at once
+	VerifyDNS          bool          // Resolve the records after writing them and warn about mismatches
+	VerifyResolver     string        // host:port of the DNS server VerifyDNS queries, empty for the system resolver
+	ManageTXT          bool          // Publish the TXT values of node annotations at the DNS records
+	TXTAnnotation      string        // Node annotation holding the TXT values for ManageTXT
+	RecordPrefix       string        // Labels prepended to every DNS_RECORD
+	RecordSuffix       string        // Labels inserted between every DNS_RECORD and DNS_ZONE
+	CreateZone         bool          // Create missing zones at startup instead of failing
+	ZoneKind           string        // Kind of created zones, Native or Master
+	ZoneNameservers    []string      // NS records of created zones
+	MaxIPsPerRecord    int           // Cap on the values of each A and AAAA RRset; 0 for no cap
+	IPSelection        string        // Which IPs MaxIPsPerRecord keeps: stable, random or round-robin
+	UserAgent          string        // User-Agent header of PowerDNS API requests
+	IPFamily           string        // Published address family: ipv4, ipv6 or all
+	StartupRetries     int           // Retries of failed startup checks before exiting
+	StartupRetryDelay  time.Duration // Wait between startup check attempts
+	RecordComment      string        // Comment template set on written RRsets, if any
+	FailureRetry       time.Duration // First retry delay after a failed sync, doubling up to SyncInterval
+	K8sMode            string        // Forced Kubernetes config source; empty tries in-cluster, then kubeconfig
+	ExtraZones         []ZoneRecords // Zone/record pairs synced in addition to DNS_ZONE and DNS_RECORD
+	AddressTypes       []string      // Node status address types to use, in order of preference
+	MergeRecords       bool          // Keep RRset values not published by this controller
+	ShuffleRecords     bool          // Randomize the order of A/AAAA values on every sync
+	LeaderElection     bool          // Only sync while holding the leader election Lease
+	LeaseNamespace     string        // Namespace of the leader election Lease
+	LeaseName          string        // Name of the leader election Lease
+	ExtraIPs           []IPAddress   // Static IPs published in addition to the discovered ones
+	StrictTTL          bool          // Fail on TTLs outside [MinTTL, MaxTTL] instead of clamping them
+	Separator          string        // Separator of annotation IP lists, in addition to commas
+	NotifySecondaries  bool          // Send NOTIFYs for the zone after records changed
+	RectifyZone        bool          // Rectify the zone after records changed
+	NoDelete           bool          // Never delete A/AAAA records or remove values from them
+	PerNodeRecords     string        // Name template of the per-node records, if enabled
 }
 
 type IPAddress struct {
-	IP      net.IP
-	IsIPv6  bool
-	String  string
+	IP         net.IP
+	IsIPv6     bool
+	String     string
+	Node       string   // Node or service the address was read from
+	NodeRecord string   // Per-node record name of the node, with PER_NODE_RECORDS
+	TXT        []string // TXT values of the node, with MANAGE_TXT
+	Priority   int      // Priority of the source for MAX_RECORDS, highest first
+	TTL        int      // TTL requested by the node's k3s.io/dns-ttl annotation, 0 if none
 }
 
+// parseIPAddresses parses a comma-separated list of IPs.
 func parseIPAddresses(ipString string) ([]IPAddress, error) {
-	if ipString == "" {
-		return nil, nil
+	return parseIPList(ipString, DefaultSeparator)
+}
+
+// parseIPList parses a list of IPs separated by commas or separator, or
+// written as a JSON array of strings. Quotes and brackets around the list and
+// its entries are ignored. Empty entries, such as from trailing or doubled
+// separators, are skipped and invalid entries are logged and dropped.
+func parseIPList(ipString, separator string) ([]IPAddress, error) {
+	var tokens []string
+	if value := strings.TrimSpace(ipString); strings.HasPrefix(value, "[") {
+		var err error
+		if tokens, err = splitJSONList(value); err != nil {
+			slog.Debug("IP list is not valid JSON, parsing it as a plain list", "value", ipString, "error", err)
+			tokens = nil
+		}
+	}
+	if tokens == nil {
+		tokens = splitList(trimEnclosing(ipString), separator)
 	}
 
 	var addresses []IPAddress
-	ipStrings := strings.Split(ipString, ",")
-
-	for _, ipStr := range ipStrings {
-		ipStr = strings.TrimSpace(ipStr)
-		if ipStr == "" {
+	for _, ipStr := range tokens {
+		if ipStr = trimEnclosing(ipStr); ipStr == "" {
 			continue
 		}
-
-		ip := net.ParseIP(ipStr)
+		ip, value := parseIPValue(ipStr)
 		if ip == nil {
-			log.Printf("Warning: invalid IP address format: %s", ipStr)
+			slog.Warn("Invalid IP address format", "ip", ipStr)
 			continue
 		}
 
@@ -73,13 +212,89 @@ func parseIPAddresses(ipString string) ([]IPAddress, error) {
 		addresses = append(addresses, IPAddress{
 			IP:     ip,
 			IsIPv6: isIPv6,
-			String: ipStr,
+			String: value,
 		})
 	}
 
+	slog.Debug("Parsed IP list", "entries", len(tokens), "valid", len(addresses))
 	return addresses, nil
 }
 
+// splitList splits value on commas and on separator, trimming whitespace
+// around each entry and dropping empty ones.
+func splitList(value, separator string) []string {
+	var tokens []string
+	for _, token := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || strings.ContainsRune(separator, r)
+	}) {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// trimEnclosing strips whitespace, quotes and brackets from around value, as
+// written by serializers that quote a whole list, such as "1.2.3.4, ::1", or
+// bracket it, such as [1.2.3.4, ::1].
+func trimEnclosing(value string) string {
+	return strings.Trim(value, " \t\r\n\"'[]")
+}
+
+// splitJSONList parses value as a JSON array of strings, such as
+// ["1.2.3.4","2001:db8::1"], trimming whitespace around each entry and
+// dropping empty ones.
+func splitJSONList(value string) ([]string, error) {
+	var entries []string
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil, err
+	}
+	var tokens []string
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			tokens = append(tokens, entry)
+		}
+	}
+	return tokens, nil
+}
+
+// parseIPValue parses a single annotation entry, tolerating an IPv6 zone
+// such as "%eth0", a trailing "/prefix" and, for IPv4, a ":port" suffix. It
+// returns the IP and its address text without them, or a nil IP if the entry
+// is invalid.
+func parseIPValue(value string) (net.IP, string) {
+	// A zone only scopes the address on this host, so it is never published
+	if start := strings.Index(value, "%"); start >= 0 {
+		end := strings.Index(value[start:], "/")
+		if end < 0 {
+			end = len(value) - start
+		}
+		value = value[:start] + value[start+end:]
+	}
+
+	if strings.Contains(value, "/") {
+		ip, _, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, ""
+		}
+		return ip, value[:strings.Index(value, "/")]
+	}
+
+	if ip := net.ParseIP(value); ip != nil {
+		return ip, value
+	}
+
+	// IPv6 addresses already contain colons, so only IPv4 may carry a port
+	host, _, err := net.SplitHostPort(value)
+	if err != nil {
+		return nil, ""
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+		return ip, host
+	}
+	return nil, ""
+}
+
 func validateDNSZone(zone string) string {
 	// Ensure zone ends with a dot (FQDN)
 	if !strings.HasSuffix(zone, ".") {
@@ -96,25 +311,84 @@ func validateDNSRecord(record string) string {
 	return record
 }
 
-func getKubernetesClient(kubeConfig string) (*kubernetes.Clientset, error) {
+// recordInZone reports whether the FQDN record equals zone or is a subdomain of it.
+func recordInZone(record, zone string) bool {
+	record = strings.ToLower(validateDNSRecord(record))
+	zone = strings.ToLower(validateDNSZone(zone))
+	return record == zone || strings.HasSuffix(record, "."+zone)
+}
+
+// affixRecordName applies RECORD_PREFIX and RECORD_SUFFIX to a DNS_RECORD
+// entry. Both are whole labels joined with single dots: the prefix goes in
+// front of the name and the suffix between the name and the zone, so with
+// zone example.com. the prefix "prod" turns nodes.example.com into
+// prod.nodes.example.com. and the suffix "eu" turns it into
+// nodes.eu.example.com. Records outside the zone only get the prefix.
+func affixRecordName(record, zone, prefix, suffix string) string {
+	record = validateDNSRecord(strings.TrimLeft(record, "."))
+	if suffix = strings.Trim(suffix, ". "); suffix != "" && recordInZone(record, zone) {
+		zone = validateDNSZone(zone)
+		if name := strings.TrimSuffix(record[:len(record)-len(zone)], "."); name != "" {
+			record = name + "." + suffix + "." + record[len(record)-len(zone):]
+		} else {
+			record = suffix + "." + record
+		}
+	}
+	if prefix = strings.Trim(prefix, ". "); prefix != "" {
+		record = prefix + "." + record
+	}
+	return record
+}
+
+// parseDNSRecords splits a comma-separated list of record names, normalizing
+// each entry to an FQDN and dropping empty or duplicate entries.
+func parseDNSRecords(value string) []string {
+	var records []string
+	seen := make(map[string]bool)
+
+	for _, record := range strings.Split(value, ",") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		record = validateDNSRecord(record)
+		if !seen[record] {
+			seen[record] = true
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+func getKubernetesClient(kubeConfig, mode string) (*kubernetes.Clientset, error) {
 	var config *rest.Config
 	var err error
 
-	if kubeConfig != "" {
+	switch {
+	case mode == K8sModeInCluster:
+		config, err = rest.InClusterConfig()
+		slog.Info("Using in-cluster Kubernetes config")
+	case kubeConfig != "":
 		// Use provided kubeconfig file
 		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
-	} else {
-		// Try in-cluster config first
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			// Fall back to default kubeconfig location
-			kubeConfigPath := os.Getenv("KUBECONFIG")
-			if kubeConfigPath == "" {
-				homeDir, _ := os.UserHomeDir()
-				kubeConfigPath = fmt.Sprintf("%s/.kube/config", homeDir)
+		slog.Info("Using explicit kubeconfig", "path", kubeConfig)
+	default:
+		// Try in-cluster config first, unless a kubeconfig was asked for
+		if mode != K8sModeKubeconfig {
+			config, err = rest.InClusterConfig()
+			if err == nil {
+				slog.Info("Using in-cluster Kubernetes config")
+				break
 			}
-			config, err = clientcmd.BuildConfigFromFlags("", kubeConfigPath)
 		}
+
+		// Fall back to default kubeconfig location
+		homeDir, _ := os.UserHomeDir()
+		kubeConfigPath := fmt.Sprintf("%s/.kube/config", homeDir)
+		config, err = clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+		slog.Info("Using default kubeconfig", "path", kubeConfigPath)
 	}
 
 	if err != nil {
@@ -129,298 +403,1929 @@ func getKubernetesClient(kubeConfig string) (*kubernetes.Clientset, error) {
 	return clientset, nil
 }
 
-func fetchExternalIPs(clientset *kubernetes.Clientset, config *Config) ([]IPAddress, error) {
-	listOptions := metav1.ListOptions{}
-	
+// fetchExternalIPs returns the IPs to publish, each attributed to the node
+// or service it was read from.
+func fetchExternalIPs(ctx context.Context, clientset kubernetes.Interface, config *Config) ([]IPAddress, error) {
+	return newIPProvider(clientset, config).GetIPs(ctx)
+}
+
+// NodeListPageSize is the number of nodes fetched per list request, so that
+// large clusters are read in chunks rather than in one heavy response.
+const NodeListPageSize = 500
+
+// fetchNodeIPs collects the deduplicated external IPs of all matching nodes.
+func fetchNodeIPs(ctx context.Context, clientset kubernetes.Interface, config *Config) ([]IPAddress, error) {
+	var nodeIPs []IPAddress
+	seenIPs := make(map[string]string)
+	count := 0
+
+	err := listNodes(ctx, clientset, config, func(node *corev1.Node) {
+		count++
+		nodeIPs = appendUniqueIPs(nodeIPs, seenIPs, nodeIPsOf(node, config), node.Name, nodePriority(node))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Found nodes matching criteria", "count", count)
+	return nodeIPs, nil
+}
+
+// listNodes calls fn for every node matching NODE_SELECTOR, in name order,
+// listing NodeListPageSize nodes at a time.
+func listNodes(ctx context.Context, clientset kubernetes.Interface, config *Config, fn func(node *corev1.Node)) error {
+	listOptions := metav1.ListOptions{Limit: NodeListPageSize}
+
 	// Apply label selector if configured
 	if config.NodeSelector != "" {
 		listOptions.LabelSelector = config.NodeSelector
-		log.Printf("Using node selector: %s", config.NodeSelector)
+		slog.Debug("Using node selector", "selector", config.NodeSelector)
 	}
-	
-	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), listOptions)
-	if err != nil {
-		if strings.Contains(err.Error(), "forbidden") {
-			return nil, fmt.Errorf("failed to list nodes due to insufficient permissions: %w\n\nThis error indicates the service account lacks proper RBAC permissions.\nPlease ensure the service account has the following permissions:\n- apiGroups: [\"\"]\n  resources: [\"nodes\"]\n  verbs: [\"get\", \"list\", \"watch\"]\n\nSee k8s-deployment.yaml for the complete RBAC configuration.", err)
-		}
-		return nil, fmt.Errorf("failed to list nodes: %w", err)
+
+	if config.NodesFile != "" {
+		return listNodesFromFile(config.NodesFile, config.NodeSelector, fn)
 	}
 
-	log.Printf("Found %d nodes matching criteria", len(nodes.Items))
-	
-	var allIPs []IPAddress
-	seenIPs := make(map[string]bool)
+	// Bound the listing so a hung API server can't stall the sync loop
+	listCtx, cancel := context.WithTimeout(ctx, config.K8sTimeout)
+	defer cancel()
 
-	for _, node := range nodes.Items {
-		externalIPAnnotation, exists := node.Annotations[ExternalIPAnnotation]
-		if !exists || externalIPAnnotation == "" {
-			log.Printf("Node %s does not have external IP annotation", node.Name)
-			continue
+	listCtx, span := startSpan(listCtx, "kubernetes.ListNodes", attribute.String("selector", config.NodeSelector))
+	pages := 0
+	for {
+		nodes, err := clientset.CoreV1().Nodes().List(listCtx, listOptions)
+		if err != nil {
+			endSpan(span, err)
+			if errors.Is(listCtx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timed out listing nodes after %s: %w", config.K8sTimeout, err)
+			}
+			if strings.Contains(err.Error(), "forbidden") {
+				return fmt.Errorf("failed to list nodes due to insufficient permissions: %w\n\nThis error indicates the service account lacks proper RBAC permissions.\nPlease ensure the service account has the following permissions:\n- apiGroups: [\"\"]\n  resources: [\"nodes\"]\n  verbs: [\"get\", \"list\", \"watch\"]\n\nSee k8s-deployment.yaml for the complete RBAC configuration.", err)
+			}
+			// An expired continue token also ends up here; the next sync
+			// starts a fresh listing
+			return fmt.Errorf("failed to list nodes: %w", err)
 		}
+		pages++
 
-		log.Printf("Processing node %s with external IPs: %s", node.Name, externalIPAnnotation)
+		for i := range nodes.Items {
+			fn(&nodes.Items[i])
+		}
 
-		ips, err := parseIPAddresses(externalIPAnnotation)
-		if err != nil {
-			log.Printf("Error parsing IPs for node %s: %v", node.Name, err)
-			continue
+		if nodes.Continue == "" {
+			break
 		}
+		listOptions.Continue = nodes.Continue
+	}
+	span.SetAttributes(attribute.Int("pages", pages))
+	endSpan(span, nil)
+	return nil
+}
 
-		for _, ip := range ips {
-			// Deduplicate IPs
-			if !seenIPs[ip.String] {
-				seenIPs[ip.String] = true
-				allIPs = append(allIPs, ip)
-			}
+// nodeIPsOf returns the filtered IPs of a node, or none if the node is
+// excluded.
+func nodeIPsOf(node *corev1.Node, config *Config) []IPAddress {
+	if nodeOptedOut(node, config.ExcludeAnnotation) {
+		slog.Debug("Skipping node", "node", node.Name, "reason", "opted out via "+config.ExcludeAnnotation)
+		return nil
+	}
+	if taint := excludingTaint(node, config.ExcludeTaints); taint != "" {
+		slog.Debug("Skipping node", "node", node.Name, "reason", "tainted with "+taint)
+		return nil
+	}
+	if config.ExcludeNotReady {
+		if reason := nodeExclusionReason(node); reason != "" {
+			slog.Debug("Skipping node", "node", node.Name, "reason", reason)
+			return nil
 		}
 	}
 
-	// Sort IPs for consistent ordering (IPv4 first, then IPv6)
-	sort.Slice(allIPs, func(i, j int) bool {
-		if allIPs[i].IsIPv6 != allIPs[j].IsIPv6 {
-			return !allIPs[i].IsIPv6 // IPv4 (false) comes before IPv6 (true)
+	var ips []IPAddress
+	if hasIPSource(config.IPSource, IPSourceAnnotation) {
+		ips = append(ips, nodeAnnotationIPs(node, config.AnnotationKeys, config.Separator)...)
+	}
+	if hasIPSource(config.IPSource, IPSourceStatus) {
+		ips = append(ips, nodeStatusIPs(node, config.AddressTypes)...)
+	}
+	ips = filterIPAddresses(ips, config)
+	if config.ManageTXT {
+		txt := nodeTXTValues(node, config.TXTAnnotation)
+		for i := range ips {
+			ips[i].TXT = txt
 		}
-		return allIPs[i].String < allIPs[j].String
-	})
+	}
+	if ttl := nodeTTL(node); ttl > 0 {
+		for i := range ips {
+			ips[i].TTL = ttl
+		}
+	}
+	if config.PerNodeRecords != "" && len(ips) > 0 {
+		if name, err := nodeRecordName(config.PerNodeRecords, node); err != nil {
+			slog.Warn("Skipping per-node record", "node", node.Name, "reason", err)
+		} else {
+			for i := range ips {
+				ips[i].NodeRecord = name
+			}
+		}
+	}
+	return ips
+}
 
-	return allIPs, nil
+// appendUniqueIPs appends the IPs not yet in seen to candidates, attributed
+// to source and with the given priority. IPs are keyed on the canonical form
+// of each address so that equivalent notations, such as compressed and
+// uncompressed IPv6, collapse to one entry. seen maps each key to the source
+// it was first read from; an IP reported by two different sources is logged
+// and counted as a duplicate.
+func appendUniqueIPs(candidates []IPAddress, seen map[string]string, ips []IPAddress, source string, priority int) []IPAddress {
+	for _, ip := range ips {
+		key := ip.IP.String()
+		if first, ok := seen[key]; ok {
+			if first != source {
+				slog.Warn("Same IP reported by multiple nodes, publishing it once", "ip", ip.String, "first", first, "duplicate", source)
+				metrics.observeDuplicateIP()
+			}
+			continue
+		}
+		seen[key] = source
+		ip.Node = source
+		ip.Priority = priority
+		candidates = append(candidates, ip)
+	}
+	return candidates
 }
 
-func updateDNSRecords(ctx context.Context, pdns *powerdns.Client, config *Config, ipAddresses []IPAddress) error {
-	// Group IP addresses by type
-	var ipv4Records []string
-	var ipv6Records []string
+// sortIPAddresses orders IPs for consistent output (IPv4 first, then IPv6).
+func sortIPAddresses(ips []IPAddress) {
+	sort.Slice(ips, func(i, j int) bool {
+		return compareIPAddresses(ips[i], ips[j]) < 0
+	})
+}
 
-	for _, ip := range ipAddresses {
-		if ip.IsIPv6 {
-			ipv6Records = append(ipv6Records, ip.String)
-		} else {
-			ipv4Records = append(ipv4Records, ip.String)
+// compareIPAddresses orders IPv4 before IPv6 and numerically within a family.
+func compareIPAddresses(a, b IPAddress) int {
+	if a.IsIPv6 != b.IsIPv6 {
+		if !a.IsIPv6 {
+			return -1 // IPv4 (false) comes before IPv6 (true)
 		}
+		return 1
 	}
+	return bytes.Compare(ipBytes(a), ipBytes(b))
+}
 
-	// Validate and ensure proper FQDN format
-	recordName := validateDNSRecord(config.DNSRecord)
-	zone := validateDNSZone(config.DNSZone)
+// ipBytes returns the 4-byte form of IPv4 addresses and the 16-byte form
+// otherwise, so addresses of one family compare consistently.
+func ipBytes(ip IPAddress) []byte {
+	if v4 := ip.IP.To4(); v4 != nil {
+		return v4
+	}
+	return ip.IP.To16()
+}
 
-	// Update A records for IPv4
-	if len(ipv4Records) > 0 {
-		log.Printf("Updating A record for %s with %d IPv4 addresses", recordName, len(ipv4Records))
-		err := pdns.Records.Change(ctx, zone, recordName, powerdns.RRTypeA, uint32(config.TTL), ipv4Records)
-		if err != nil {
-			return fmt.Errorf("failed to update A record: %w", err)
-		}
-		log.Printf("Successfully updated A record for %s", recordName)
-	} else {
-		// Delete existing A records if no IPv4 addresses
-		log.Printf("No IPv4 addresses found, deleting A record for %s", recordName)
-		err := pdns.Records.Delete(ctx, zone, recordName, powerdns.RRTypeA)
-		if err != nil {
-			// Check if it's a "not found" error and log accordingly
-			if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
-				log.Printf("A record for %s does not exist (already deleted)", recordName)
-			} else {
-				log.Printf("Warning: failed to delete A record: %v", err)
-			}
+// selectByPriority keeps the max candidates from the highest-priority nodes,
+// breaking ties by the regular IP ordering, and returns the rest as dropped.
+func selectByPriority(candidates []IPAddress, max int) (kept, dropped []IPAddress) {
+	sorted := append([]IPAddress(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
 		}
+		return compareIPAddresses(sorted[i], sorted[j]) < 0
+	})
+	return sorted[:max], sorted[max:]
+}
+
+// nodeOptedOut reports whether the node carries the opt-out annotation set to true.
+func nodeOptedOut(node *corev1.Node, annotationKey string) bool {
+	value, exists := node.Annotations[annotationKey]
+	if !exists {
+		return false
 	}
+	excluded, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		slog.Warn("Invalid opt-out annotation value, ignoring", "node", node.Name, "annotation", annotationKey, "value", value)
+		return false
+	}
+	return excluded
+}
 
-	// Update AAAA records for IPv6
-	if len(ipv6Records) > 0 {
-		log.Printf("Updating AAAA record for %s with %d IPv6 addresses", recordName, len(ipv6Records))
-		err := pdns.Records.Change(ctx, zone, recordName, powerdns.RRTypeAAAA, uint32(config.TTL), ipv6Records)
-		if err != nil {
-			return fmt.Errorf("failed to update AAAA record: %w", err)
-		}
-		log.Printf("Successfully updated AAAA record for %s", recordName)
-	} else {
-		// Delete existing AAAA records if no IPv6 addresses
-		log.Printf("No IPv6 addresses found, deleting AAAA record for %s", recordName)
-		err := pdns.Records.Delete(ctx, zone, recordName, powerdns.RRTypeAAAA)
-		if err != nil {
-			// Check if it's a "not found" error and log accordingly
-			if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
-				log.Printf("AAAA record for %s does not exist (already deleted)", recordName)
-			} else {
-				log.Printf("Warning: failed to delete AAAA record: %v", err)
+// excludingTaint returns the first taint of the node whose key is one of
+// taintKeys, formatted like kubectl does, or an empty string if none is.
+func excludingTaint(node *corev1.Node, taintKeys []string) string {
+	for _, taint := range node.Spec.Taints {
+		for _, key := range taintKeys {
+			if taint.Key == key {
+				return taint.ToString()
 			}
 		}
 	}
-
-	return nil
+	return ""
 }
 
-func loadConfig() (*Config, error) {
-	config := &Config{
-		SyncInterval: DefaultSyncInterval,
-		TTL:          DefaultTTL,
+// nodePriority reads the node's DNS priority annotation, defaulting to 0.
+func nodePriority(node *corev1.Node) int {
+	value, exists := node.Annotations[PriorityAnnotation]
+	if !exists || value == "" {
+		return 0
 	}
+	priority, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		slog.Warn("Invalid DNS priority annotation, using 0", "node", node.Name, "annotation", PriorityAnnotation, "value", value)
+		return 0
+	}
+	return priority
+}
 
-	if url := os.Getenv("POWERDNS_URL"); url != "" {
-		config.PowerDNSURL = url
-	} else {
-		return nil, fmt.Errorf("POWERDNS_URL environment variable is required")
+// nodeTTL returns the TTL in seconds set by the node's k3s.io/dns-ttl
+// annotation, as a duration or plain seconds, or 0 if it is unset or invalid.
+func nodeTTL(node *corev1.Node) int {
+	value := strings.TrimSpace(node.Annotations[TTLAnnotation])
+	if value == "" {
+		return 0
+	}
+	ttl, err := parseTTL(value)
+	if err != nil || ttl < MinTTL || ttl > MaxTTL {
+		slog.Warn("Invalid DNS TTL annotation, ignoring", "node", node.Name, "annotation", TTLAnnotation, "value", value)
+		return 0
 	}
+	return ttl
+}
 
-	if apiKey := os.Getenv("POWERDNS_API_KEY"); apiKey != "" {
-		config.PowerDNSAPIKey = apiKey
-	} else {
-		return nil, fmt.Errorf("POWERDNS_API_KEY environment variable is required")
+// nodeAnnotationIPs returns the addresses listed in the first of
+// annotationKeys that is set on the node.
+func nodeAnnotationIPs(node *corev1.Node, annotationKeys []string, separator string) []IPAddress {
+	var annotationKey, externalIPAnnotation string
+	for _, annotationKey = range annotationKeys {
+		if externalIPAnnotation = node.Annotations[annotationKey]; externalIPAnnotation != "" {
+			break
+		}
+	}
+	if externalIPAnnotation == "" {
+		slog.Debug("Node does not have external IP annotation", "node", node.Name, "annotations", strings.Join(annotationKeys, ","))
+		return nil
 	}
 
-	if vhost := os.Getenv("POWERDNS_VHOST"); vhost != "" {
-		config.PowerDNSVHost = vhost
-	} else {
-		// Default to localhost if not specified
-		config.PowerDNSVHost = "localhost"
+	slog.Debug("Processing node external IPs", "node", node.Name, "annotation", annotationKey, "ips", externalIPAnnotation)
+
+	ips, err := parseIPList(externalIPAnnotation, separator)
+	if err != nil {
+		slog.Error("Error parsing IPs for node", "node", node.Name, "error", err)
+		return nil
 	}
+	return ips
+}
 
-	if zone := os.Getenv("DNS_ZONE"); zone != "" {
-		config.DNSZone = validateDNSZone(zone)
-	} else {
-		return nil, fmt.Errorf("DNS_ZONE environment variable is required")
+// nodeStatusIPs returns the addresses reported in the node's status of the
+// first type in addressTypes that the node has.
+func nodeStatusIPs(node *corev1.Node, addressTypes []string) []IPAddress {
+	addressType, statusIPs := nodeStatusAddresses(node, addressTypes)
+	if len(statusIPs) == 0 {
+		slog.Debug("Node does not report any of the configured address types in its status", "node", node.Name, "address_types", addressTypes)
+		return nil
 	}
 
-	if record := os.Getenv("DNS_RECORD"); record != "" {
-		config.DNSRecord = validateDNSRecord(record)
-	} else {
-		return nil, fmt.Errorf("DNS_RECORD environment variable is required")
+	slog.Debug("Processing node status IPs", "node", node.Name, "type", addressType, "ips", strings.Join(statusIPs, ","))
+
+	ips, err := parseIPAddresses(strings.Join(statusIPs, ","))
+	if err != nil {
+		slog.Error("Error parsing status IPs for node", "node", node.Name, "error", err)
+		return nil
 	}
+	return ips
+}
 
-	if interval := os.Getenv("SYNC_INTERVAL"); interval != "" {
-		if duration, err := time.ParseDuration(interval); err == nil {
-			config.SyncInterval = duration
-		} else {
-			log.Printf("Warning: invalid SYNC_INTERVAL format, using default: %v", DefaultSyncInterval)
+// nodeStatusAddresses returns the addresses of the first of addressTypes the
+// node reports in its status, and that type.
+func nodeStatusAddresses(node *corev1.Node, addressTypes []string) (string, []string) {
+	for _, addressType := range addressTypes {
+		var addresses []string
+		for _, address := range node.Status.Addresses {
+			if string(address.Type) == addressType {
+				addresses = append(addresses, address.Address)
+			}
+		}
+		if len(addresses) > 0 {
+			return addressType, addresses
 		}
 	}
+	return "", nil
+}
 
-	if ttlStr := os.Getenv("DNS_TTL"); ttlStr != "" {
-		if ttl, err := time.ParseDuration(ttlStr); err == nil {
-			config.TTL = int(ttl.Seconds())
-		} else {
-			log.Printf("Warning: invalid DNS_TTL format, using default: %d seconds", DefaultTTL)
+// parseAddressTypes parses ADDRESS_TYPES, an ordered, comma-separated list of
+// node address types. Only the IP address types are accepted.
+func parseAddressTypes(value string) ([]string, error) {
+	var types []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		switch {
+		case strings.EqualFold(entry, string(corev1.NodeExternalIP)):
+			types = append(types, string(corev1.NodeExternalIP))
+		case strings.EqualFold(entry, string(corev1.NodeInternalIP)):
+			types = append(types, string(corev1.NodeInternalIP))
+		default:
+			return nil, fmt.Errorf("invalid address type %q: must be %s or %s", entry, corev1.NodeExternalIP, corev1.NodeInternalIP)
 		}
 	}
+	return types, nil
+}
 
-	config.KubeConfig = os.Getenv("KUBECONFIG")
-	config.NodeSelector = os.Getenv("NODE_SELECTOR")
-
-	return config, nil
+// inIPFamily reports whether ip belongs to the IP_FAMILY family.
+func inIPFamily(family string, ip IPAddress) bool {
+	switch family {
+	case IPFamilyIPv4:
+		return !ip.IsIPv6
+	case IPFamilyIPv6:
+		return ip.IsIPv6
+	default:
+		return true
+	}
 }
 
-func syncDNSRecords(ctx context.Context, clientset *kubernetes.Clientset, pdns *powerdns.Client, config *Config) error {
-	log.Println("Fetching external IP addresses from Kubernetes nodes...")
+// filterIPAddresses drops addresses that must not be published according to
+// the configured filters.
+func filterIPAddresses(ips []IPAddress, config *Config) []IPAddress {
+	var filtered []IPAddress
+	for _, ip := range ips {
+		if !inIPFamily(config.IPFamily, ip) {
+			slog.Debug("Excluding address", "ip", ip.String, "reason", "not in IP_FAMILY "+config.IPFamily)
+			continue
+		}
 
-	ips, err := fetchExternalIPs(clientset, config)
-	if err != nil {
-		return fmt.Errorf("failed to fetch external IPs: %w", err)
+		if config.ExcludePrivate {
+			if reason := nonPublicReason(ip.IP); reason != "" {
+				slog.Debug("Excluding address", "ip", ip.String, "reason", reason)
+				continue
+			}
+		}
+
+		if config.ExcludeLinkLocal && (ip.IP.IsLinkLocalUnicast() || ip.IP.IsLinkLocalMulticast()) {
+			slog.Debug("Excluding address", "ip", ip.String, "reason", "link-local address")
+			continue
+		}
+
+		if len(config.IncludeCIDRs) > 0 && !cidrsContain(config.IncludeCIDRs, ip.IP) {
+			slog.Debug("Excluding address", "ip", ip.String, "reason", "not in INCLUDE_CIDRS")
+			continue
+		}
+
+		if cidrsContain(config.ExcludeCIDRs, ip.IP) {
+			slog.Debug("Excluding address", "ip", ip.String, "reason", "in EXCLUDE_CIDRS")
+			continue
+		}
+
+		filtered = append(filtered, ip)
 	}
+	return filtered
+}
 
-	if len(ips) == 0 {
-		log.Println("No external IP addresses found")
-		// Still try to clean up existing records
-		return updateDNSRecords(ctx, pdns, config, ips)
+// cidrsContain reports whether any of the networks contains ip.
+func cidrsContain(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
 	}
+	return false
+}
 
-	log.Printf("Found %d external IP addresses:", len(ips))
-	for _, ip := range ips {
-		ipType := "IPv4"
-		if ip.IsIPv6 {
-			ipType = "IPv6"
+// parseCIDRs parses a comma-separated list of CIDR ranges.
+func parseCIDRs(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(value, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
 		}
-		log.Printf("  %s (%s)", ip.String, ipType)
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// nonPublicReason returns why ip is not publicly routable, or an empty string
+// if it is.
+func nonPublicReason(ip net.IP) string {
+	switch {
+	case ip.IsPrivate():
+		return "private address"
+	case ip.IsLoopback():
+		return "loopback address"
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return "link-local address"
+	default:
+		return ""
 	}
+}
 
-	log.Printf("Updating DNS records for %s in zone %s...", config.DNSRecord, config.DNSZone)
+// nodeExclusionReason returns why a node should not receive traffic, or an
+// empty string if it is schedulable and Ready.
+func nodeExclusionReason(node *corev1.Node) string {
+	if node.Spec.Unschedulable {
+		return "node is unschedulable (cordoned)"
+	}
 
-	err = updateDNSRecords(ctx, pdns, config, ips)
-	if err != nil {
-		return fmt.Errorf("failed to update DNS records: %w", err)
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			if condition.Status != corev1.ConditionTrue {
+				return fmt.Sprintf("node is not Ready (status %s)", condition.Status)
+			}
+			return ""
+		}
 	}
 
-	return nil
+	return "node has no Ready condition"
 }
 
-func main() {
-	log.Printf("Starting k8s-external-ip-powerdns sync service...")
-	log.Printf("Version: %s, Commit: %s, Build Date: %s", version, commit, buildDate)
+// updateDNSRecords publishes the records of one zone to every provider and
+// returns what it did to them.
+func updateDNSRecords(ctx context.Context, providers []DNSProvider, config *Config, ipAddresses []IPAddress) (*SyncSummary, error) {
+	config = nodeTTLConfig(config, ipAddresses)
 
-	config, err := loadConfig()
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+	// Select once, so that every provider gets the same random subset
+	ipv4Records, ipv6Records, dropped := familyRecordValues(config, ipAddresses)
+	if len(dropped) > 0 {
+		slog.Warn("Too many IPs for one record, leaving some out", "zone", config.DNSZone, "max_ips_per_record", config.MaxIPsPerRecord, "strategy", config.IPSelection, "dropped", strings.Join(dropped, ", "))
 	}
 
-	log.Printf("Configuration loaded:")
-	log.Printf("  PowerDNS URL: %s", config.PowerDNSURL)
-	log.Printf("  PowerDNS VHost: %s", config.PowerDNSVHost)
-	log.Printf("  DNS Zone: %s", config.DNSZone)
-	log.Printf("  DNS Record: %s", config.DNSRecord)
-	log.Printf("  DNS TTL: %d seconds", config.TTL)
-	log.Printf("  Sync Interval: %v", config.SyncInterval)
-	if config.NodeSelector != "" {
-		log.Printf("  Node Selector: %s", config.NodeSelector)
-	} else {
-		log.Printf("  Node Selector: <all nodes>")
+	collector := newSyncCollector()
+
+	// Push to every provider; the sync only fails if none of them succeeds
+	var errs []error
+	for _, provider := range providers {
+		if err := updateTargetRecords(ctx, collector, provider, config, ipAddresses, ipv4Records, ipv6Records); err != nil {
+			slog.Error("Failed to update PowerDNS target", "target", provider.Name(), "kind", powerDNSErrorKind(err), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			reportZoneSerials(ctx, collector, provider)
+			continue
+		}
+		slog.Info("Updated PowerDNS target", "target", provider.Name())
+		if finisher, ok := provider.(zoneFinisher); ok && collector.changedAt(provider) {
+			finisher.FinishZoneUpdate(ctx, finishOptions(config), validateDNSZone(config.DNSZone))
+		}
+		reportZoneSerials(ctx, collector, provider)
 	}
 
-	clientset, err := getKubernetesClient(config.KubeConfig)
-	if err != nil {
-		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	summary := collector.summary()
+	if len(errs) == len(providers) {
+		return summary, errors.Join(errs...)
 	}
 
-	// Test Kubernetes permissions before starting
-	ctx := context.Background()
-	log.Println("Verifying Kubernetes permissions...")
-	_, err = clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
-	if err != nil {
-		log.Fatalf("Failed to access Kubernetes nodes - check service account permissions: %v\n\nRequired RBAC permissions:\n- apiGroups: [\"\"]\n  resources: [\"nodes\"]\n  verbs: [\"get\", \"list\", \"watch\"]\n\nSee k8s-deployment.yaml for proper RBAC configuration.", err)
-	}
-	log.Println("Kubernetes permissions verified successfully")
-
-	// Initialize PowerDNS client with proper options
-	pdns := powerdns.New(
-		config.PowerDNSURL,
-		config.PowerDNSVHost,
-		powerdns.WithAPIKey(config.PowerDNSAPIKey),
-		powerdns.WithHTTPClient(&http.Client{
-			Timeout: 30 * time.Second,
-		}),
-	)
+	if config.VerifyDNS && !config.DryRun {
+		verifyLiveRecords(ctx, newVerifyResolver(config.VerifyResolver), collector, config, time.Now())
+	}
 
-	// Test PowerDNS connection
-	servers, err := pdns.Servers.List(ctx)
-	if err != nil {
-		log.Fatalf("Failed to connect to PowerDNS API: %v", err)
+	metrics.setPublishedIPs(len(ipAddresses))
+	return summary, nil
+}
+
+// familyRecordValues groups addresses into A and AAAA record values. With
+// MAX_IPS_PER_RECORD, only the values chosen by IP_SELECTION_STRATEGY are
+// kept for each family and the rest are returned as dropped.
+func familyRecordValues(config *Config, ipAddresses []IPAddress) (ipv4Records, ipv6Records, dropped []string) {
+	for _, ip := range ipAddresses {
+		if ip.IsIPv6 {
+			ipv6Records = append(ipv6Records, ip.String)
+		} else {
+			ipv4Records = append(ipv4Records, ip.String)
+		}
 	}
-	log.Printf("Connected to PowerDNS API, found %d servers", len(servers))
 
-	// Verify zone exists
-	_, err = pdns.Zones.Get(ctx, config.DNSZone)
-	if err != nil {
-		log.Fatalf("Failed to access DNS zone %s: %v", config.DNSZone, err)
+	ipv4Records, droppedIPv4 := selectRecordValues(config.IPSelection, ipv4Records, config.MaxIPsPerRecord, selectionRound)
+	ipv6Records, droppedIPv6 := selectRecordValues(config.IPSelection, ipv6Records, config.MaxIPsPerRecord, selectionRound)
+	return ipv4Records, ipv6Records, append(droppedIPv4, droppedIPv6...)
+}
+
+// updateTargetRecords publishes all configured records to a single provider:
+// ipv4Records and ipv6Records at the DNS records, and the per-node, PTR and
+// TXT records derived from ipAddresses.
+func updateTargetRecords(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, ipAddresses []IPAddress, ipv4Records, ipv6Records []string) error {
+	zone := validateDNSZone(config.DNSZone)
+
+	var txtRecords []string
+	if config.ManageTXT {
+		txtRecords = txtRecordValues(ipAddresses)
 	}
-	log.Printf("Successfully verified DNS zone: %s", config.DNSZone)
 
-	// Perform initial sync
-	log.Println("Performing initial DNS sync...")
-	if err := syncDNSRecords(ctx, clientset, pdns, config); err != nil {
-		log.Fatalf("Initial sync failed: %v", err)
+	shared := sharedRecordConfig(config)
+	for _, record := range config.DNSRecords {
+		// Stop between records on shutdown rather than in the middle of one
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync interrupted before updating %s: %w", record, err)
+		}
+		if shared.ManageA || shared.ManageAAAA {
+			if err := updateDNSRecord(ctx, collector, provider, shared, zone, validateDNSRecord(record), ipv4Records, ipv6Records); err != nil {
+				slog.Error("Failed to update record", "record", record, "error", err, "nodes", nodeAttribution(ipAddresses))
+				return err
+			}
+		}
+		if config.ManageTXT {
+			if err := updateTXTRecord(ctx, collector, provider, config, zone, validateDNSRecord(record), txtRecords); err != nil {
+				return err
+			}
+		}
 	}
-	log.Println("Initial sync completed successfully")
 
-	// Set up periodic sync
-	ticker := time.NewTicker(config.SyncInterval)
-	defer ticker.Stop()
+	if config.DNSRecordA != "" && config.ManageA {
+		if err := updateDNSRecord(ctx, collector, provider, familyRecordConfig(config, RecordTypeA), zone, config.DNSRecordA, ipv4Records, nil); err != nil {
+			slog.Error("Failed to update record", "record", config.DNSRecordA, "error", err, "nodes", nodeAttribution(ipAddresses))
+			return err
+		}
+	}
+	if config.DNSRecordAAAA != "" && config.ManageAAAA {
+		if err := updateDNSRecord(ctx, collector, provider, familyRecordConfig(config, RecordTypeAAAA), zone, config.DNSRecordAAAA, nil, ipv6Records); err != nil {
+			slog.Error("Failed to update record", "record", config.DNSRecordAAAA, "error", err, "nodes", nodeAttribution(ipAddresses))
+			return err
+		}
+	}
 
-	log.Printf("Starting periodic sync every %v...", config.SyncInterval)
+	if config.PerNodeRecords != "" {
+		if err := updatePerNodeRecords(ctx, collector, provider, config, zone, ipAddresses); err != nil {
+			return err
+		}
+	}
 
-	for {
-		select {
-		case <-ticker.C:
-			if err := syncDNSRecords(ctx, clientset, pdns, config); err != nil {
-				log.Printf("Sync failed: %v", err)
-			}
+	if config.CNAME != "" {
+		target := validateDNSRecord(config.DNSRecords[0])
+		slog.Info("Updating CNAME record", "record", config.CNAME, "target", target)
+		if err := changeRecord(ctx, collector, provider, config, zone, config.CNAME, RecordTypeCNAME, []string{target}); err != nil {
+			return fmt.Errorf("failed to update CNAME record for %s: %w", config.CNAME, err)
+		}
+	}
+
+	if config.ManagePTR {
+		updatePTRRecords(ctx, collector, provider, config, ipv4Records, ipv6Records)
+	}
+
+	return nil
+}
+
+// sharedRecordConfig returns the config for the DNS_RECORD names, which do
+// not manage the families published at their own DNS_RECORD_A or
+// DNS_RECORD_AAAA name.
+func sharedRecordConfig(config *Config) *Config {
+	if config.DNSRecordA == "" && config.DNSRecordAAAA == "" {
+		return config
+	}
+	shared := *config
+	if config.DNSRecordA != "" {
+		shared.ManageA = false
+	}
+	if config.DNSRecordAAAA != "" {
+		shared.ManageAAAA = false
+	}
+	return &shared
+}
+
+// familyRecordConfig returns the config for the DNS_RECORD_A or
+// DNS_RECORD_AAAA name, which manages only recordType, so that each family
+// is written and cleaned up on its own.
+func familyRecordConfig(config *Config, recordType RecordType) *Config {
+	family := *config
+	family.ManageA = recordType == RecordTypeA
+	family.ManageAAAA = recordType == RecordTypeAAAA
+	return &family
+}
+
+// updateDNSRecord publishes the A and AAAA record sets for a single record name.
+func updateDNSRecord(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone, recordName string, ipv4Records, ipv6Records []string) (err error) {
+	// With TXT ownership, never touch records owned by someone else and only
+	// delete records that were already ours before this sync
+	canDelete := true
+	if config.TXTOwnerID != "" {
+		state, err := claimOwnership(ctx, collector, provider, config, zone, recordName)
+		if err != nil {
+			return err
+		}
+		if state == ownershipForeign {
+			slog.Warn("Record is owned by another controller, skipping", "record", recordName)
+			return nil
+		}
+		canDelete = state == ownershipOurs
+	}
+
+	// With MERGE_RECORDS, keep values that this controller did not publish
+	if config.MergeRecords {
+		var managed map[RecordType][]string
+		if managed, err = readManagedSet(ctx, provider, config, zone, recordName); err != nil {
+			return err
+		}
+
+		pending := make(map[RecordType][]string, len(managed))
+		final := make(map[RecordType][]string, len(managed))
+		for recordType, values := range managed {
+			pending[recordType] = values
+			final[recordType] = values
+		}
+		if config.ManageA {
+			pending[RecordTypeA] = mergeRecordValues(ipv4Records, nil, managed[RecordTypeA])
+			final[RecordTypeA] = ipv4Records
+			if ipv4Records, err = mergedRecordValues(ctx, provider, config, zone, recordName, RecordTypeA, managed[RecordTypeA], ipv4Records); err != nil {
+				return err
+			}
+		}
+		if config.ManageAAAA {
+			pending[RecordTypeAAAA] = mergeRecordValues(ipv6Records, nil, managed[RecordTypeAAAA])
+			final[RecordTypeAAAA] = ipv6Records
+			if ipv6Records, err = mergedRecordValues(ctx, provider, config, zone, recordName, RecordTypeAAAA, managed[RecordTypeAAAA], ipv6Records); err != nil {
+				return err
+			}
+		}
+
+		if err = writeManagedSet(ctx, collector, provider, config, zone, recordName, pending); err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = writeManagedSet(ctx, collector, provider, config, zone, recordName, final)
+			}
+		}()
+	}
+
+	// With NO_DELETE, keep every existing value so RRsets only ever grow
+	if config.NoDelete {
+		if config.ManageA {
+			if ipv4Records, err = keepExistingValues(ctx, provider, config, zone, recordName, RecordTypeA, ipv4Records); err != nil {
+				return err
+			}
+		}
+		if config.ManageAAAA {
+			if ipv6Records, err = keepExistingValues(ctx, provider, config, zone, recordName, RecordTypeAAAA, ipv6Records); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Update A records for IPv4, unless A records are managed out of band
+	if config.ManageA {
+		if len(ipv4Records) > 0 {
+			slog.Debug("Updating A record", "record", recordName, "ips", len(ipv4Records))
+			if err := changeRecord(ctx, collector, provider, config, zone, recordName, RecordTypeA, orderRecordValues(config, ipv4Records)); err != nil {
+				return fmt.Errorf("failed to update A record for %s: %w", recordName, err)
+			}
+		} else if config.NoDelete {
+			slog.Info("No IPv4 addresses found, but deletion is suppressed by NO_DELETE", "record", recordName)
+		} else if canDelete {
+			// Delete existing A records if no IPv4 addresses
+			slog.Info("No IPv4 addresses found, deleting A record", "record", recordName)
+			deleteRecord(ctx, collector, provider, config, zone, recordName, RecordTypeA)
+		} else {
+			slog.Info("No IPv4 addresses found, but record was not owned yet, skipping delete", "record", recordName)
+		}
+	}
+
+	// Update AAAA records for IPv6, unless AAAA records are managed out of band
+	if config.ManageAAAA {
+		if len(ipv6Records) > 0 {
+			slog.Debug("Updating AAAA record", "record", recordName, "ips", len(ipv6Records))
+			if err := changeRecord(ctx, collector, provider, config, zone, recordName, RecordTypeAAAA, orderRecordValues(config, ipv6Records)); err != nil {
+				return fmt.Errorf("failed to update AAAA record for %s: %w", recordName, err)
+			}
+		} else if config.NoDelete {
+			slog.Info("No IPv6 addresses found, but deletion is suppressed by NO_DELETE", "record", recordName)
+		} else if canDelete {
+			// Delete existing AAAA records if no IPv6 addresses
+			slog.Info("No IPv6 addresses found, deleting AAAA record", "record", recordName)
+			deleteRecord(ctx, collector, provider, config, zone, recordName, RecordTypeAAAA)
+		} else {
+			slog.Info("No IPv6 addresses found, but record was not owned yet, skipping delete", "record", recordName)
+		}
+	}
+
+	return nil
+}
+
+// keepExistingValues returns current followed by the values of the existing
+// RRset that are not in current, logging the values whose removal NO_DELETE
+// suppressed.
+func keepExistingValues(ctx context.Context, provider DNSProvider, config *Config, zone, recordName string, recordType RecordType, current []string) ([]string, error) {
+	values, err := mergedRecordValues(ctx, provider, config, zone, recordName, recordType, nil, current)
+	if err != nil {
+		return nil, err
+	}
+	if kept := values[len(current):]; len(kept) > 0 {
+		slog.Info("Deletion suppressed by NO_DELETE, keeping record values", "type", recordType, "record", recordName, "values", strings.Join(kept, ", "))
+	}
+	return values, nil
+}
+
+// changeRecord replaces the RRset of the given type, or only logs the
+// intended change in dry-run mode.
+func changeRecord(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone, recordName string, recordType RecordType, values []string) error {
+	// Skip identical writes, which would still bump the zone serial and send
+	// NOTIFYs. Shuffled address records are always written, since the
+	// comparison ignores order and would otherwise never rotate them. Both
+	// sides are normalized, as PowerDNS returns values in canonical form.
+	values = normalizeRecordValues(recordType, values)
+	existing, ttl, found, err := getRecordValues(ctx, provider, config, zone, recordName, recordType)
+	if err != nil {
+		slog.Warn("Failed to read current record, updating unconditionally", "type", recordType, "record", recordName, "error", err)
+	} else if found && ttl == uint32(recordTTL(config, recordType)) && !shuffleRecordType(config, recordType) && recordValuesEqual(normalizeRecordValues(recordType, existing), values) {
+		slog.Debug("Record already up to date", "type", recordType, "record", recordName)
+		collector.record(provider, recordName, recordType, outcomeUnchanged, values)
+		return nil
+	}
+	reason, outcome := EventReasonRecordUpdated, outcomeUpdated
+	if err == nil && !found {
+		reason, outcome = EventReasonRecordCreated, outcomeCreated
+	}
+
+	if config.DryRun {
+		slog.Info("[dry-run] Would set record", "type", recordType, "record", recordName, "ttl", recordTTL(config, recordType), "values", strings.Join(values, ", "))
+		collector.record(provider, recordName, recordType, outcome, values)
+		return nil
+	}
+
+	noteZoneSerial(ctx, collector, provider, zone)
+	err = retryPowerDNS(ctx, config.PowerDNSMaxRetries, fmt.Sprintf("updating %s record for %s", recordType, recordName), func() error {
+		return provider.EnsureRecords(ctx, zone, recordName, recordType, uint32(recordTTL(config, recordType)), values)
+	})
+	if err != nil {
+		return err
+	}
+	collector.record(provider, recordName, recordType, outcome, values)
+	slog.Info("Successfully updated record", "type", recordType, "record", recordName)
+	eventEmitter.recordChange(reason, "Set %s record %s to %d IP(s)", recordType, recordName, len(values))
+	return nil
+}
+
+// deleteRecord removes the RRset of the given type. Failures are logged
+// rather than returned since a missing record is the desired end state.
+func deleteRecord(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone, recordName string, recordType RecordType) {
+	if _, _, found, err := getRecordValues(ctx, provider, config, zone, recordName, recordType); err == nil && !found {
+		slog.Debug("Record does not exist (already deleted)", "type", recordType, "record", recordName)
+		collector.record(provider, recordName, recordType, outcomeUnchanged, nil)
+		return
+	}
+
+	if config.DryRun {
+		slog.Info("[dry-run] Would delete record", "type", recordType, "record", recordName)
+		collector.record(provider, recordName, recordType, outcomeDeleted, nil)
+		return
+	}
+
+	noteZoneSerial(ctx, collector, provider, zone)
+	err := retryPowerDNS(ctx, config.PowerDNSMaxRetries, fmt.Sprintf("deleting %s record for %s", recordType, recordName), func() error {
+		return provider.DeleteRecords(ctx, zone, recordName, recordType)
+	})
+	if err != nil {
+		if isNotFound(err) {
+			slog.Debug("Record does not exist (already deleted)", "type", recordType, "record", recordName)
+			collector.record(provider, recordName, recordType, outcomeUnchanged, nil)
+		} else {
+			slog.Warn("Failed to delete record", "type", recordType, "record", recordName, "kind", powerDNSErrorKind(err), "error", err)
+		}
+		return
+	}
+	collector.record(provider, recordName, recordType, outcomeDeleted, nil)
+	slog.Info("Successfully deleted record", "type", recordType, "record", recordName)
+	eventEmitter.recordChange(EventReasonRecordDeleted, "Deleted %s record %s, no IPs remain", recordType, recordName)
+}
+
+// orderRecordValues returns values in the order to publish them: shuffled
+// with SHUFFLE_RECORDS so that round-robin clients spread their load, else
+// unchanged.
+func orderRecordValues(config *Config, values []string) []string {
+	if !config.ShuffleRecords {
+		return values
+	}
+	shuffled := append([]string(nil), values...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// shuffleRecordType reports whether records of the given type are shuffled.
+func shuffleRecordType(config *Config, recordType RecordType) bool {
+	return config.ShuffleRecords && (recordType == RecordTypeA || recordType == RecordTypeAAAA)
+}
+
+// recordSpanAttributes describes the RRset a PowerDNS call acts on.
+func recordSpanAttributes(zone, recordName string, recordType RecordType) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("dns.zone", zone),
+		attribute.String("dns.record", recordName),
+		attribute.String("dns.type", string(recordType)),
+	}
+}
+
+// nodeTTLConfig returns config with the lowest k3s.io/dns-ttl annotation
+// among the nodes contributing ipAddresses as the TTL of every record type
+// whose configured TTL is higher, so that e.g. a node being drained can
+// shorten failover. The annotation never raises a TTL. Without such an
+// annotation, or one lowering no TTL, it returns config itself.
+func nodeTTLConfig(config *Config, ipAddresses []IPAddress) *Config {
+	ttl, node := 0, ""
+	for _, ip := range ipAddresses {
+		if ip.TTL > 0 && (ttl == 0 || ip.TTL < ttl) {
+			ttl, node = ip.TTL, ip.Node
+		}
+	}
+	if ttl == 0 {
+		return config
+	}
+
+	ttlA, ttlAAAA := recordTTL(config, RecordTypeA), recordTTL(config, RecordTypeAAAA)
+	if ttl >= config.TTL && ttl >= ttlA && ttl >= ttlAAAA {
+		slog.Debug("Ignoring TTL from node annotation, it is not lower than the configured TTL", "node", node, "ttl_seconds", ttl, "annotation", TTLAnnotation)
+		return config
+	}
+
+	slog.Debug("Using TTL from node annotation", "node", node, "ttl_seconds", ttl, "annotation", TTLAnnotation)
+	override := *config
+	override.TTL = min(config.TTL, ttl)
+	override.TTLA = min(ttlA, ttl)
+	override.TTLAAAA = min(ttlAAAA, ttl)
+	return &override
+}
+
+// recordTTL returns the TTL to publish records of the given type with.
+func recordTTL(config *Config, recordType RecordType) int {
+	switch {
+	case recordType == RecordTypeA && config.TTLA > 0:
+		return config.TTLA
+	case recordType == RecordTypeAAAA && config.TTLAAAA > 0:
+		return config.TTLAAAA
+	default:
+		return config.TTL
+	}
+}
+
+// getRecordValues returns the current contents and TTL of the RRset of the
+// given type, and whether it exists at all.
+func getRecordValues(ctx context.Context, provider DNSProvider, config *Config, zone, recordName string, recordType RecordType) ([]string, uint32, bool, error) {
+	var values []string
+	var ttl uint32
+	err := retryPowerDNS(ctx, config.PowerDNSMaxRetries, fmt.Sprintf("reading %s record for %s", recordType, recordName), func() error {
+		var err error
+		values, ttl, err = provider.GetRecords(ctx, zone, recordName, recordType)
+		return err
+	})
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return values, ttl, len(values) > 0, nil
+}
+
+// recordValuesEqual reports whether two record value lists contain the same
+// values, ignoring order.
+func recordValuesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func loadConfig() (*Config, error) {
+	config := &Config{
+		SyncInterval:       DefaultSyncInterval,
+		K8sTimeout:         DefaultK8sTimeout,
+		FailureRetry:       DefaultFailureRetryInterval,
+		MinWriteInterval:   DefaultMinWriteInterval,
+		PowerDNSTimeout:    DefaultPowerDNSTimeout,
+		WebhookThreshold:   DefaultWebhookThreshold,
+		IPFamily:           IPFamilyAll,
+		Separator:          DefaultSeparator,
+		AddressTypes:       []string{string(corev1.NodeExternalIP)},
+		StartupRetries:     DefaultStartupRetries,
+		StartupRetryDelay:  DefaultStartupRetryInterval,
+		TTL:                DefaultTTL,
+		MetricsAddr:        DefaultMetricsAddr,
+		HealthAddr:         DefaultHealthAddr,
+		PowerDNSMaxRetries: DefaultPowerDNSMaxRetries,
+		IPSource:           IPSourceAnnotation,
+		AnnotationKeys:     []string{ExternalIPAnnotation},
+		ExcludeAnnotation:  ExcludeAnnotation,
+	}
+
+	if urls := parsePowerDNSURLs(getEnv("POWERDNS_URL")); len(urls) > 0 {
+		config.PowerDNSURLs = urls
+	} else {
+		return nil, fmt.Errorf("POWERDNS_URL environment variable is required")
+	}
+
+	if keyFile := getEnv("POWERDNS_API_KEY_FILE"); keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read POWERDNS_API_KEY_FILE: %w", err)
+		}
+		apiKey := strings.TrimSpace(string(data))
+		if apiKey == "" {
+			return nil, fmt.Errorf("POWERDNS_API_KEY_FILE %s is empty", keyFile)
+		}
+		config.PowerDNSAPIKey = apiKey
+	} else if apiKey := getEnv("POWERDNS_API_KEY"); apiKey != "" {
+		config.PowerDNSAPIKey = apiKey
+	} else {
+		return nil, fmt.Errorf("POWERDNS_API_KEY or POWERDNS_API_KEY_FILE environment variable is required")
+	}
+
+	if serverID := getEnv("POWERDNS_SERVER_ID"); serverID != "" {
+		config.PowerDNSServerID = serverID
+	} else if vhost := getEnv("POWERDNS_VHOST"); vhost != "" {
+		// POWERDNS_VHOST was always used as the server id, despite its name
+		slog.Warn("POWERDNS_VHOST is deprecated, use POWERDNS_SERVER_ID instead")
+		config.PowerDNSServerID = vhost
+	} else {
+		// Default to localhost if not specified
+		config.PowerDNSServerID = "localhost"
+	}
+
+	if zone := getEnv("DNS_ZONE"); zone != "" {
+		config.DNSZone = validateDNSZone(zone)
+	} else {
+		return nil, fmt.Errorf("DNS_ZONE environment variable is required")
+	}
+
+	config.RecordPrefix = strings.Trim(getEnv("RECORD_PREFIX"), ". ")
+	config.RecordSuffix = strings.Trim(getEnv("RECORD_SUFFIX"), ". ")
+	var records []string
+	for _, record := range strings.Split(getEnv("DNS_RECORD"), ",") {
+		if record = strings.TrimSpace(record); record != "" {
+			records = append(records, affixRecordName(record, config.DNSZone, config.RecordPrefix, config.RecordSuffix))
+		}
+	}
+	if records := parseDNSRecords(strings.Join(records, ",")); len(records) > 0 {
+		config.DNSRecords = records
+	} else {
+		return nil, fmt.Errorf("DNS_RECORD environment variable is required")
+	}
+
+	for _, record := range config.DNSRecords {
+		if !recordInZone(record, config.DNSZone) {
+			return nil, fmt.Errorf("DNS_RECORD %s is not within DNS_ZONE %s", record, config.DNSZone)
+		}
+	}
+
+	pairs, err := parseZoneRecords(getEnv("ZONE_RECORDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ZONE_RECORDS: %w", err)
+	}
+	config.ExtraZones = pairs
+
+	if cname := getEnv("DNS_CNAME"); cname != "" {
+		config.CNAME = validateDNSRecord(strings.TrimSpace(cname))
+		if !recordInZone(config.CNAME, config.DNSZone) {
+			return nil, fmt.Errorf("DNS_CNAME %s is not within DNS_ZONE %s", config.CNAME, config.DNSZone)
+		}
+		// A CNAME cannot coexist with the A/AAAA records at the same name
+		for _, record := range config.DNSRecords {
+			if strings.EqualFold(config.CNAME, record) {
+				return nil, fmt.Errorf("DNS_CNAME %s must differ from DNS_RECORD", config.CNAME)
+			}
+		}
+	}
+
+	for key, field := range map[string]*string{"DNS_RECORD_A": &config.DNSRecordA, "DNS_RECORD_AAAA": &config.DNSRecordAAAA} {
+		record := strings.TrimSpace(getEnv(key))
+		if record == "" {
+			continue
+		}
+		*field = validateDNSRecord(affixRecordName(record, config.DNSZone, config.RecordPrefix, config.RecordSuffix))
+		if !recordInZone(*field, config.DNSZone) {
+			return nil, fmt.Errorf("%s %s is not within DNS_ZONE %s", key, *field, config.DNSZone)
+		}
+		if strings.EqualFold(*field, config.CNAME) {
+			return nil, fmt.Errorf("%s %s must differ from DNS_CNAME", key, *field)
+		}
+	}
+
+	if template := getEnv("PER_NODE_RECORDS"); template != "" {
+		if err := validateNodeRecordTemplate(template); err != nil {
+			return nil, fmt.Errorf("invalid PER_NODE_RECORDS: %w", err)
+		}
+		config.PerNodeRecords = template
+	}
+
+	if interval := getEnv("SYNC_INTERVAL"); interval != "" {
+		if duration, err := time.ParseDuration(interval); err == nil {
+			config.SyncInterval = duration
+		} else {
+			slog.Warn("Invalid SYNC_INTERVAL format, using default", "default", DefaultSyncInterval)
+		}
+	}
+
+	if ttlStr := getEnv("DNS_TTL"); ttlStr != "" {
+		if ttl, err := parseTTL(ttlStr); err == nil {
+			config.TTL = ttl
+		} else {
+			slog.Warn("Invalid DNS_TTL format, using default", "value", ttlStr, "default_seconds", DefaultTTL)
+		}
+	}
+
+	// Per-type overrides fall back to the global TTL when unset or invalid
+	if ttlStr := getEnv("DNS_TTL_A"); ttlStr != "" {
+		if ttl, err := parseTTL(ttlStr); err == nil {
+			config.TTLA = ttl
+		} else {
+			slog.Warn("Invalid DNS_TTL_A format, using DNS_TTL", "value", ttlStr, "ttl_seconds", config.TTL)
+		}
+	}
+
+	if ttlStr := getEnv("DNS_TTL_AAAA"); ttlStr != "" {
+		if ttl, err := parseTTL(ttlStr); err == nil {
+			config.TTLAAAA = ttl
+		} else {
+			slog.Warn("Invalid DNS_TTL_AAAA format, using DNS_TTL", "value", ttlStr, "ttl_seconds", config.TTL)
+		}
+	}
+
+	// Keep TTLs in a sane range: 0 hammers resolvers, huge values prevent failover
+	config.StrictTTL = getEnvBool("STRICT_TTL", false)
+	if config.TTL, err = checkTTL("DNS_TTL", config.TTL, config.StrictTTL); err != nil {
+		return nil, err
+	}
+	if config.TTLA != 0 {
+		if config.TTLA, err = checkTTL("DNS_TTL_A", config.TTLA, config.StrictTTL); err != nil {
+			return nil, err
+		}
+	}
+	if config.TTLAAAA != 0 {
+		if config.TTLAAAA, err = checkTTL("DNS_TTL_AAAA", config.TTLAAAA, config.StrictTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	config.KubeConfig = getEnv("KUBECONFIG")
+	if mode := getEnv("K8S_MODE"); mode != "" {
+		switch mode = strings.ToLower(mode); mode {
+		case K8sModeInCluster, K8sModeKubeconfig:
+		default:
+			return nil, fmt.Errorf("invalid K8S_MODE %q: must be %s or %s", mode, K8sModeInCluster, K8sModeKubeconfig)
+		}
+		config.K8sMode = mode
+	}
+	config.NodeSelector = getEnv("NODE_SELECTOR")
+	if config.NodeSelector != "" {
+		// Fail fast on a bad selector rather than silently matching nothing
+		if _, err := labels.Parse(config.NodeSelector); err != nil {
+			return nil, fmt.Errorf("invalid NODE_SELECTOR %q: %w", config.NodeSelector, err)
+		}
+	}
+	config.ExcludeNotReady = getEnvBool("EXCLUDE_NOTREADY", false)
+
+	if keys := splitList(getEnv("ANNOTATION_KEY"), ""); len(keys) > 0 {
+		config.AnnotationKeys = keys
+	}
+
+	if key := getEnv("EXCLUDE_ANNOTATION"); key != "" {
+		config.ExcludeAnnotation = key
+	}
+	config.ExcludeTaints = splitList(getEnv("EXCLUDE_TAINTS"), "")
+
+	if source := getEnv("IP_SOURCE"); source != "" {
+		normalized, err := parseIPSource(source)
+		if err != nil {
+			return nil, err
+		}
+		config.IPSource = normalized
+	}
+
+	if separator := getEnv("SEPARATOR"); separator != "" {
+		if len([]rune(separator)) != 1 || strings.ContainsAny(separator, ".:/[] ") {
+			return nil, fmt.Errorf("invalid SEPARATOR %q: must be a single character that cannot appear in an IP", separator)
+		}
+		config.Separator = separator
+	}
+
+	if value := getEnv("EXTRA_IPS"); value != "" {
+		ips, err := parseIPAddresses(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXTRA_IPS: %w", err)
+		}
+		config.ExtraIPs = ips
+	}
+
+	if value := getEnv("ADDRESS_TYPES"); value != "" {
+		types, err := parseAddressTypes(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ADDRESS_TYPES: %w", err)
+		}
+		if len(types) > 0 {
+			config.AddressTypes = types
+		}
+	}
+
+	if hasIPSource(config.IPSource, IPSourceService) {
+		config.ServiceNamespace = getEnv("SERVICE_NAMESPACE")
+		if config.ServiceNamespace == "" {
+			config.ServiceNamespace = DefaultServiceNamespace
+		}
+		config.ServiceName = getEnv("SERVICE_NAME")
+		if config.ServiceName == "" {
+			return nil, fmt.Errorf("SERVICE_NAME is required when IP_SOURCE includes %s", IPSourceService)
+		}
+	}
+
+	config.ExcludePrivate = getEnvBool("EXCLUDE_PRIVATE", false)
+	config.ExcludeLinkLocal = getEnvBool("EXCLUDE_LINK_LOCAL", true)
+
+	includeCIDRs, err := parseCIDRs(getEnv("INCLUDE_CIDRS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid INCLUDE_CIDRS: %w", err)
+	}
+	config.IncludeCIDRs = includeCIDRs
+
+	excludeCIDRs, err := parseCIDRs(getEnv("EXCLUDE_CIDRS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXCLUDE_CIDRS: %w", err)
+	}
+	config.ExcludeCIDRs = excludeCIDRs
+
+	config.ManagePTR = getEnvBool("MANAGE_PTR", false)
+	config.MergeRecords = getEnvBool("MERGE_RECORDS", false)
+	config.NoDelete = getEnvBool("NO_DELETE", false)
+	config.ShuffleRecords = getEnvBool("SHUFFLE_RECORDS", false)
+	config.CreateZone = getEnvBool("CREATE_ZONE", false)
+	if config.ZoneKind, err = parseZoneKind(getEnv("ZONE_KIND")); err != nil {
+		return nil, err
+	}
+	config.ZoneNameservers = parseDNSRecords(getEnv("ZONE_NAMESERVERS"))
+	if config.CreateZone && len(config.ZoneNameservers) == 0 {
+		return nil, fmt.Errorf("CREATE_ZONE requires ZONE_NAMESERVERS, the NS records of the created zone")
+	}
+
+	config.NotifySecondaries = getEnvBool("NOTIFY_SECONDARIES", false)
+	config.RectifyZone = getEnvBool("RECTIFY_ZONE", false)
+
+	config.LeaderElection = getEnvBool("LEADER_ELECTION", false)
+	if config.LeaderElection {
+		config.LeaseName = getEnv("LEASE_NAME")
+		if config.LeaseName == "" {
+			config.LeaseName = DefaultLeaseName
+		}
+		// Default to the controller's own namespace, where its RBAC lives
+		config.LeaseNamespace = getEnv("LEASE_NAMESPACE")
+		if config.LeaseNamespace == "" {
+			config.LeaseNamespace = getEnv("POD_NAMESPACE")
+		}
+		if config.LeaseNamespace == "" {
+			config.LeaseNamespace = "default"
+		}
+	}
+	config.DryRun = getEnvBool("DRY_RUN", false)
+	config.WatchMode = getEnvBool("WATCH_MODE", false)
+	config.RunOnce = getEnvBool("RUN_ONCE", false)
+	config.Dump = getEnvBool("DUMP", false)
+	config.StartupSelfTest = getEnvBool("STARTUP_SELFTEST", false)
+	config.ManageA = getEnvBool("MANAGE_A", true)
+	config.ManageAAAA = getEnvBool("MANAGE_AAAA", true)
+
+	// Records of a family that is not published are left completely untouched
+	if family := getEnv("IP_FAMILY"); family != "" {
+		switch family = strings.ToLower(family); family {
+		case IPFamilyIPv4:
+			config.ManageAAAA = false
+		case IPFamilyIPv6:
+			config.ManageA = false
+		case IPFamilyAll:
+		default:
+			return nil, fmt.Errorf("invalid IP_FAMILY %q: must be one of %s, %s or %s", family, IPFamilyIPv4, IPFamilyIPv6, IPFamilyAll)
+		}
+		config.IPFamily = family
+	}
+
+	if !config.ManageA && !config.ManageAAAA {
+		return nil, fmt.Errorf("MANAGE_A and MANAGE_AAAA cannot both be false, or IP_FAMILY excludes the only managed type")
+	}
+
+	config.TXTOwnerID = strings.TrimSpace(getEnv("TXT_OWNER_ID"))
+	if strings.ContainsAny(config.TXTOwnerID, ",\"") {
+		return nil, fmt.Errorf("TXT_OWNER_ID %q must not contain commas or quotes", config.TXTOwnerID)
+	}
+
+	// Both would own the TXT RRset of the DNS records
+	config.ManageTXT = getEnvBool("MANAGE_TXT", false)
+	if config.ManageTXT && config.TXTOwnerID != "" {
+		return nil, fmt.Errorf("MANAGE_TXT cannot be combined with TXT_OWNER_ID, which keeps ownership records in the same TXT RRset")
+	}
+	config.TXTAnnotation = DefaultTXTAnnotation
+	if key := getEnv("TXT_ANNOTATION"); key != "" {
+		config.TXTAnnotation = key
+	}
+
+	if addr := getEnv("METRICS_ADDR"); addr != "" {
+		config.MetricsAddr = addr
+	}
+
+	if addr := getEnv("HEALTH_ADDR"); addr != "" {
+		config.HealthAddr = addr
+	}
+
+	if retries := getEnv("POWERDNS_MAX_RETRIES"); retries != "" {
+		if n, err := strconv.Atoi(retries); err == nil && n >= 0 {
+			config.PowerDNSMaxRetries = n
+		} else {
+			slog.Warn("Invalid POWERDNS_MAX_RETRIES value, using default", "default", DefaultPowerDNSMaxRetries)
+		}
+	}
+
+	config.PowerDNSClientCert = getEnv("POWERDNS_CLIENT_CERT")
+	config.PowerDNSClientKey = getEnv("POWERDNS_CLIENT_KEY")
+	config.PowerDNSCACert = getEnv("POWERDNS_CA_CERT")
+	config.PowerDNSInsecure = getEnvBool("POWERDNS_INSECURE_SKIP_VERIFY", false)
+	config.UserAgent = defaultUserAgent()
+	if userAgent := strings.TrimSpace(getEnv("USER_AGENT")); userAgent != "" {
+		config.UserAgent = userAgent
+	}
+	if (config.PowerDNSClientCert == "") != (config.PowerDNSClientKey == "") {
+		return nil, fmt.Errorf("POWERDNS_CLIENT_CERT and POWERDNS_CLIENT_KEY must be set together")
+	}
+
+	if timeout := getEnv("POWERDNS_TIMEOUT"); timeout != "" {
+		if duration, err := time.ParseDuration(timeout); err == nil && duration > 0 {
+			config.PowerDNSTimeout = duration
+		} else {
+			slog.Warn("Invalid POWERDNS_TIMEOUT format, using default", "default", DefaultPowerDNSTimeout)
+		}
+	}
+
+	if timeout := getEnv("K8S_TIMEOUT"); timeout != "" {
+		if duration, err := time.ParseDuration(timeout); err == nil && duration > 0 {
+			config.K8sTimeout = duration
+		} else {
+			slog.Warn("Invalid K8S_TIMEOUT format, using default", "default", DefaultK8sTimeout)
+		}
+	}
+
+	if retry := getEnv("FAILURE_RETRY_INTERVAL"); retry != "" {
+		if duration, err := time.ParseDuration(retry); err == nil && duration > 0 {
+			config.FailureRetry = duration
+		} else {
+			slog.Warn("Invalid FAILURE_RETRY_INTERVAL format, using default", "default", DefaultFailureRetryInterval)
+		}
+	}
+
+	if interval := getEnv("MIN_WRITE_INTERVAL"); interval != "" {
+		if duration, err := time.ParseDuration(interval); err == nil && duration >= 0 {
+			config.MinWriteInterval = duration
+		} else {
+			slog.Warn("Invalid MIN_WRITE_INTERVAL format, using default", "default", DefaultMinWriteInterval)
+		}
+	}
+
+	if grace := getEnv("REMOVAL_GRACE_PERIOD"); grace != "" {
+		if duration, err := time.ParseDuration(grace); err == nil && duration >= 0 {
+			config.RemovalGrace = duration
+		} else {
+			slog.Warn("Invalid REMOVAL_GRACE_PERIOD format, removing IPs at once")
+		}
+	}
+
+	config.VerifyDNS = getEnvBool("VERIFY_DNS", false)
+	if resolver := getEnv("VERIFY_RESOLVER"); resolver != "" {
+		config.VerifyResolver = parseVerifyResolver(resolver)
+	}
+
+	if jitter := getEnv("SYNC_JITTER"); jitter != "" {
+		if f, err := strconv.ParseFloat(jitter, 64); err == nil && f >= 0 {
+			config.SyncJitter = f
+		} else {
+			slog.Warn("Invalid SYNC_JITTER value, syncing without jitter")
+		}
+	}
+
+	config.RecordComment = getEnv("RECORD_COMMENT")
+	config.WebhookURL = getEnv("WEBHOOK_URL")
+	config.SyncToken = getEnv("SYNC_TOKEN")
+	if threshold := getEnv("WEBHOOK_FAILURE_THRESHOLD"); threshold != "" {
+		if n, err := strconv.Atoi(threshold); err == nil && n > 0 {
+			config.WebhookThreshold = n
+		} else {
+			slog.Warn("Invalid WEBHOOK_FAILURE_THRESHOLD value, using default", "default", DefaultWebhookThreshold)
+		}
+	}
+
+	if threshold := getEnv("READY_FAILURE_THRESHOLD"); threshold != "" {
+		n, err := strconv.Atoi(threshold)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid READY_FAILURE_THRESHOLD %q: must be a non-negative integer", threshold)
+		}
+		config.ReadyFailures = n
+	}
+
+	if minRecords := getEnv("MIN_RECORDS"); minRecords != "" {
+		n, err := strconv.Atoi(minRecords)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid MIN_RECORDS %q: must be a non-negative integer", minRecords)
+		}
+		config.MinRecords = n
+	}
+
+	if maxDelete := getEnv("MAX_DELETE_GUARD"); maxDelete != "" {
+		n, err := strconv.Atoi(maxDelete)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid MAX_DELETE_GUARD %q: must be a non-negative integer", maxDelete)
+		}
+		config.MaxDeleteGuard = n
+	}
+	config.GuardOverride = getEnvBool("SAFETY_GUARD_OVERRIDE", false)
+
+	if retries := getEnv("STARTUP_RETRIES"); retries != "" {
+		if n, err := strconv.Atoi(retries); err == nil && n >= 0 {
+			config.StartupRetries = n
+		} else {
+			slog.Warn("Invalid STARTUP_RETRIES value, using default", "default", DefaultStartupRetries)
+		}
+	}
+
+	if interval := getEnv("STARTUP_RETRY_INTERVAL"); interval != "" {
+		if duration, err := time.ParseDuration(interval); err == nil && duration > 0 {
+			config.StartupRetryDelay = duration
+		} else {
+			slog.Warn("Invalid STARTUP_RETRY_INTERVAL format, using default", "default", DefaultStartupRetryInterval)
+		}
+	}
+
+	if maxRecords := getEnv("MAX_RECORDS"); maxRecords != "" {
+		if n, err := strconv.Atoi(maxRecords); err == nil && n >= 0 {
+			config.MaxRecords = n
+		} else {
+			slog.Warn("Invalid MAX_RECORDS value, publishing all IPs")
+		}
+	}
+
+	config.IPSelection = SelectionStable
+	if strategy := getEnv("IP_SELECTION_STRATEGY"); strategy != "" {
+		if config.IPSelection, err = parseSelectionStrategy(strings.ToLower(strategy)); err != nil {
+			return nil, fmt.Errorf("invalid IP_SELECTION_STRATEGY %q: %w", strategy, err)
+		}
+	}
+
+	if maxIPs := getEnv("MAX_IPS_PER_RECORD"); maxIPs != "" {
+		if n, err := strconv.Atoi(maxIPs); err == nil && n >= 0 {
+			config.MaxIPsPerRecord = n
+		} else {
+			slog.Warn("Invalid MAX_IPS_PER_RECORD value, not limiting record sizes")
+		}
+	}
+
+	// NODES_FILE replaces the cluster, so nothing else may need one
+	config.NodesFile = getEnv("NODES_FILE")
+	if config.NodesFile != "" {
+		switch {
+		case hasIPSource(config.IPSource, IPSourceService):
+			return nil, fmt.Errorf("NODES_FILE cannot be combined with IP_SOURCE=%s, which reads a Service from the cluster", IPSourceService)
+		case config.WatchMode:
+			return nil, fmt.Errorf("NODES_FILE cannot be combined with WATCH_MODE")
+		case config.LeaderElection:
+			return nil, fmt.Errorf("NODES_FILE cannot be combined with LEADER_ELECTION")
+		}
+	}
+
+	return config, nil
+}
+
+// nextSyncDelay returns the wait before the next periodic sync, spreading
+// syncs of many instances over up to SyncJitter extra intervals.
+func nextSyncDelay(config *Config) time.Duration {
+	if config.SyncJitter <= 0 {
+		// wait.Jitter treats a zero factor as 1.0
+		return config.SyncInterval
+	}
+	return wait.Jitter(config.SyncInterval, config.SyncJitter)
+}
+
+// failureRetryDelay returns the wait before retrying after the given number
+// of consecutive failed syncs: FailureRetry, doubling with each failure and
+// capped at SyncInterval.
+func failureRetryDelay(config *Config, failures int) time.Duration {
+	delay := config.FailureRetry
+	for i := 1; i < failures && delay < config.SyncInterval; i++ {
+		delay *= 2
+	}
+	if delay > config.SyncInterval {
+		delay = config.SyncInterval
+	}
+	return delay
+}
+
+// parseTTL parses a TTL given either as a duration such as "5m" or as a
+// plain number of seconds such as "300", and returns it in seconds.
+func parseTTL(value string) (int, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return seconds, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return int(ttl.Seconds()), nil
+}
+
+// checkTTL returns ttl if it is within [MinTTL, MaxTTL]. Otherwise it fails
+// when strict is set, or returns ttl clamped to the range.
+func checkTTL(name string, ttl int, strict bool) (int, error) {
+	clamped := min(max(ttl, MinTTL), MaxTTL)
+	if clamped == ttl {
+		return ttl, nil
+	}
+	if strict {
+		return 0, fmt.Errorf("%s of %ds is outside the allowed range of %d-%ds", name, ttl, MinTTL, MaxTTL)
+	}
+	slog.Warn("TTL out of range, clamping", "setting", name, "ttl_seconds", ttl, "effective_seconds", clamped)
+	return clamped, nil
+}
+
+// getEnvBool parses a boolean environment variable, falling back to the
+// default when it is unset or invalid.
+func getEnvBool(key string, defaultValue bool) bool {
+	value := getEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("Invalid boolean value, using default", "variable", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// syncDNSRecords publishes the IPs returned by ipProvider as they are now.
+// The desired records are recomputed on every sync from a single fresh node
+// list rather than patched from earlier results or watch events, so a node
+// deleted meanwhile simply drops out and its IPs are never republished.
+func syncDNSRecords(ctx context.Context, ipProvider IPProvider, providers []DNSProvider, config *Config) (err error) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, "syncDNSRecords")
+	defer func() {
+		endSpan(span, err)
+		metrics.observeSync(time.Since(start), err)
+		syncState.recordSync(err)
+	}()
+	summary := newSyncSummary()
+	lastSyncSummary = summary
+
+	slog.Debug("Fetching external IP addresses from Kubernetes nodes")
+
+	ips, err := ipProvider.GetIPs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch external IPs: %w", err)
+	}
+	ips = applyRemovalGrace(ips, config.RemovalGrace, time.Now())
+	syncState.recordFetch(ipStrings(ips), ipSources(ips))
+
+	if len(ips) == 0 {
+		// Still try to clean up existing records
+		slog.Info("No external IP addresses found")
+	} else {
+		var ipv4, ipv6 []IPAddress
+		for _, ip := range ips {
+			if ip.IsIPv6 {
+				ipv6 = append(ipv6, ip)
+			} else {
+				ipv4 = append(ipv4, ip)
+			}
+		}
+		slog.Info("Found external IP addresses", "count", len(ips), "ipv4", ipStrings(ipv4), "ipv6", ipStrings(ipv6), "nodes", nodeAttribution(ips))
+	}
+
+	previous := lastPublishedIPs
+	if config.MaxDeleteGuard > 0 {
+		previous = publishedIPs(ctx, providers, config, lastPublishedIPs)
+	}
+	if err := checkSafetyGuards(config, previous, ips); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, zoneConfig := range zoneConfigs(config) {
+		slog.Debug("Updating DNS records", "records", strings.Join(zoneConfig.DNSRecords, ", "), "zone", zoneConfig.DNSZone)
+
+		zoneSummary, err := updateDNSRecords(ctx, providers, zoneConfig, ips)
+		summary.add(zoneSummary)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("zone %s: %w", zoneConfig.DNSZone, err))
+		}
+	}
+	slog.Info("Sync summary", "changed", summary.Changed(), "dry_run", config.DryRun, "records", summary.String())
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to update DNS records: %w", errors.Join(errs...))
+	}
+	lastPublishedIPs = canonicalIPStrings(ips)
+	selectionRound++
+
+	return nil
+}
+
+func main() {
+	overrides, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if errors.Is(err, errVersion) {
+		fmt.Printf("k8s-external-ip-powerdns %s (commit %s, built %s)\n", version, commit, buildDate)
+		return
+	} else if err != nil {
+		os.Exit(ExitUsage)
+	}
+	configOverrides = overrides
+
+	// Load the file first so it can also set the log format and level
+	if path := getEnv("CONFIG_FILE"); path != "" {
+		values, err := loadConfigFile(path)
+		if err != nil {
+			fatal("Failed to load config file", "path", path, "error", err)
+		}
+		fileValues = values
+	}
+
+	if err := configureLogging(getEnv("LOG_FORMAT"), getEnv("LOG_LEVEL")); err != nil {
+		fatal("Failed to configure logging", "error", err)
+	}
+
+	slog.Info("Starting k8s-external-ip-powerdns sync service")
+	slog.Info("Build information", "version", version, "commit", commit, "build_date", buildDate)
+
+	config, err := loadConfig()
+	if err != nil {
+		fatal("Failed to load configuration", "error", err)
+	}
+
+	// Cancelled on SIGTERM/SIGINT to stop the sync loop gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
+	defer func() {
+		// Flush buffered spans, bounded so an unreachable collector can't block exit
+		flushCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
+	nodeSelector := config.NodeSelector
+	if nodeSelector == "" {
+		nodeSelector = "<all nodes>"
+	}
+	slog.Info("Configuration loaded",
+		"powerdns_urls", strings.Join(config.PowerDNSURLs, ", "),
+		"powerdns_server_id", config.PowerDNSServerID,
+		"zone", config.DNSZone,
+		"records", config.DNSRecords,
+		"record_prefix", config.RecordPrefix,
+		"record_suffix", config.RecordSuffix,
+		"extra_zones", len(config.ExtraZones),
+		"address_types", config.AddressTypes,
+		"extra_ips", ipStrings(config.ExtraIPs),
+		"separator", config.Separator,
+		"per_node_records", config.PerNodeRecords,
+		"ttl_seconds", config.TTL,
+		"ttl_a_seconds", recordTTL(config, RecordTypeA),
+		"ttl_aaaa_seconds", recordTTL(config, RecordTypeAAAA),
+		"strict_ttl", config.StrictTTL,
+		"sync_interval", config.SyncInterval,
+		"powerdns_max_retries", config.PowerDNSMaxRetries,
+		"node_selector", nodeSelector,
+		"ip_source", config.IPSource,
+		"service", config.ServiceNamespace+"/"+config.ServiceName,
+		"annotation_keys", config.AnnotationKeys,
+		"exclude_annotation", config.ExcludeAnnotation,
+		"exclude_taints", config.ExcludeTaints,
+		"sync_jitter", config.SyncJitter,
+		"k8s_timeout", config.K8sTimeout,
+		"powerdns_timeout", config.PowerDNSTimeout,
+		"startup_retries", config.StartupRetries,
+		"startup_retry_interval", config.StartupRetryDelay,
+		"powerdns_client_cert", config.PowerDNSClientCert,
+		"powerdns_ca_cert", config.PowerDNSCACert,
+		"powerdns_insecure_skip_verify", config.PowerDNSInsecure,
+		"user_agent", config.UserAgent,
+		"exclude_notready", config.ExcludeNotReady,
+		"exclude_private", config.ExcludePrivate,
+		"exclude_link_local", config.ExcludeLinkLocal,
+		"include_cidrs", config.IncludeCIDRs,
+		"exclude_cidrs", config.ExcludeCIDRs,
+		"manage_ptr", config.ManagePTR,
+		"merge_records", config.MergeRecords,
+		"no_delete", config.NoDelete,
+		"shuffle_records", config.ShuffleRecords,
+		"notify_secondaries", config.NotifySecondaries,
+		"create_zone", config.CreateZone,
+		"zone_kind", config.ZoneKind,
+		"zone_nameservers", config.ZoneNameservers,
+		"rectify_zone", config.RectifyZone,
+		"leader_election", config.LeaderElection,
+		"lease_namespace", config.LeaseNamespace,
+		"lease_name", config.LeaseName,
+		"dry_run", config.DryRun,
+		"watch_mode", config.WatchMode,
+		"nodes_file", config.NodesFile,
+		"run_once", config.RunOnce,
+		"dump", config.Dump,
+		"startup_selftest", config.StartupSelfTest,
+		"max_records", config.MaxRecords,
+		"max_ips_per_record", config.MaxIPsPerRecord,
+		"ip_selection_strategy", config.IPSelection,
+		"cname", config.CNAME,
+		"dns_record_a", config.DNSRecordA,
+		"dns_record_aaaa", config.DNSRecordAAAA,
+		"txt_owner_id", config.TXTOwnerID,
+		"record_comment", config.RecordComment,
+		"failure_retry_interval", config.FailureRetry,
+		"min_write_interval", config.MinWriteInterval,
+		"removal_grace_period", config.RemovalGrace,
+		"verify_dns", config.VerifyDNS,
+		"verify_resolver", config.VerifyResolver,
+		"k8s_mode", config.K8sMode,
+		"ip_family", config.IPFamily,
+		"manage_a", config.ManageA,
+		"manage_txt", config.ManageTXT,
+		"txt_annotation", config.TXTAnnotation,
+		"manage_aaaa", config.ManageAAAA,
+		"min_records", config.MinRecords,
+		"max_delete_guard", config.MaxDeleteGuard,
+		"safety_guard_override", config.GuardOverride,
+		"webhook_enabled", config.WebhookURL != "",
+		"sync_token_set", config.SyncToken != "",
+		"webhook_failure_threshold", config.WebhookThreshold,
+		"ready_failure_threshold", config.ReadyFailures,
+		"metrics_addr", config.MetricsAddr,
+		"health_addr", config.HealthAddr,
+	)
+	if config.DryRun {
+		slog.Warn("Dry run enabled: no changes will be sent to PowerDNS")
+	}
+	if config.PowerDNSInsecure {
+		slog.Warn("TLS certificate verification for PowerDNS is DISABLED: connections can be intercepted, use only for lab or internal setups")
+	}
+
+	if config.NodesFile != "" {
+		slog.Warn("Reading nodes from NODES_FILE instead of the Kubernetes API: for development and testing only", "path", config.NodesFile)
+	}
+
+	if config.Dump {
+		var clientset kubernetes.Interface
+		if config.NodesFile == "" {
+			if clientset, err = getKubernetesClient(config.KubeConfig, config.K8sMode); err != nil {
+				fatal("Failed to create Kubernetes client", "error", err)
+			}
+		}
+		if err := runDump(ctx, os.Stdout, clientset, config); err != nil {
+			fatal("Failed to dump records", "error", err)
+		}
+		return
+	}
+
+	// Start the HTTP endpoints before any sync so failures are observable
+	endpoints := newHTTPServers()
+	endpoints.Handle(config.MetricsAddr, "/metrics", metrics)
+	endpoints.Handle(config.HealthAddr, "/healthz", syncState.healthzHandler())
+	endpoints.Handle(config.HealthAddr, "/readyz", syncState.readyzHandler(config.SyncInterval, config.ReadyFailures))
+	endpoints.Handle(config.HealthAddr, "/status", syncState.statusHandler())
+	endpoints.Handle(config.HealthAddr, "/config", configHandler())
+	endpoints.Handle(config.HealthAddr, "/sync", syncHandler())
+	effectiveConfig.Store(config)
+	endpoints.Start()
+
+	// NODES_FILE replaces the cluster, so there are no permissions to check
+	// nor Pod to attach events to
+	var clientset kubernetes.Interface
+	if config.NodesFile == "" {
+		clientset, err = getKubernetesClient(config.KubeConfig, config.K8sMode)
+		if err != nil {
+			fatal("Failed to create Kubernetes client", "error", err)
+		}
+
+		// Test Kubernetes permissions before starting
+		slog.Info("Verifying Kubernetes permissions")
+		if hasIPSource(config.IPSource, IPSourceAnnotation) || hasIPSource(config.IPSource, IPSourceStatus) {
+			err = retryStartup(ctx, config.StartupRetries, config.StartupRetryDelay, "list nodes", func() error {
+				permCtx, cancel := context.WithTimeout(ctx, config.K8sTimeout)
+				defer cancel()
+				_, err := clientset.CoreV1().Nodes().List(permCtx, metav1.ListOptions{Limit: 1})
+				return err
+			})
+			if ctx.Err() != nil {
+				slog.Info("Shutting down: termination signal received during startup")
+				return
+			}
+			if err != nil {
+				fatalWithCode(kubernetesExitCode(err), "Failed to access Kubernetes nodes - check service account permissions", "error", err, "hint", "Required RBAC permissions:\n- apiGroups: [\"\"]\n  resources: [\"nodes\"]\n  verbs: [\"get\", \"list\", \"watch\"]\n\nSee k8s-deployment.yaml for proper RBAC configuration.")
+			}
+		}
+		if hasIPSource(config.IPSource, IPSourceService) {
+			err = retryStartup(ctx, config.StartupRetries, config.StartupRetryDelay, "get service", func() error {
+				_, err := fetchServiceIPs(ctx, clientset, config)
+				return err
+			})
+			if ctx.Err() != nil {
+				slog.Info("Shutting down: termination signal received during startup")
+				return
+			}
+			if err != nil {
+				fatalWithCode(kubernetesExitCode(err), "Failed to access the LoadBalancer Service - check it exists and the service account may get services", "error", err)
+			}
+		}
+		slog.Info("Kubernetes permissions verified successfully")
+
+		eventEmitter = newEventEmitter(clientset, getEnv("POD_NAMESPACE"), getEnv("POD_NAME"))
+		defer eventEmitter.Shutdown()
+	}
+
+	// Initialize one PowerDNS client per configured server
+	targets, err := newPowerDNSTargets(config)
+	if err != nil {
+		fatal("Failed to set up PowerDNS client TLS", "error", err)
+	}
+
+	// Test PowerDNS connections and verify the zone exists
+	err = retryStartup(ctx, config.StartupRetries, config.StartupRetryDelay, "verify PowerDNS", func() error {
+		return verifyPowerDNSTargets(ctx, targets, config)
+	})
+	if ctx.Err() != nil {
+		slog.Info("Shutting down: termination signal received during startup")
+		return
+	}
+	if err != nil {
+		fatalWithCode(ExitConnectionFailed, "No PowerDNS server is usable", "error", err)
+	}
+
+	if config.StartupSelfTest {
+		if config.DryRun {
+			slog.Info("[dry-run] Skipping startup self-test, it writes to PowerDNS")
+		} else {
+			for _, target := range targets {
+				if err := runSelfTest(ctx, target, config); err != nil {
+					fatal("Startup self-test failed - check the API key has write access to the zone", "target", target.URL, "error", err)
+				}
+			}
+		}
+	}
+
+	providers := dnsProviders(targets)
+	if config.LeaderElection {
+		runWithLeaderElection(ctx, clientset, config, func(ctx context.Context) {
+			runController(ctx, clientset, providers, config)
+		})
+	} else {
+		runController(ctx, clientset, providers, config)
+	}
+
+	if ctx.Err() != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		endpoints.Shutdown(shutdownCtx)
+		cancel()
+		slog.Info("Shutdown complete")
+	}
+}
+
+// runController performs the initial sync and then keeps the records in sync
+// until ctx is cancelled, or returns right away in run-once mode.
+func runController(ctx context.Context, clientset kubernetes.Interface, providers []DNSProvider, config *Config) {
+	// Perform initial sync
+	slog.Info("Performing initial DNS sync")
+	ipProvider := newIPProvider(clientset, config)
+	if err := syncDNSRecords(ctx, ipProvider, providers, config); err != nil {
+		if ctx.Err() != nil {
+			slog.Info("Shutting down: termination signal received during initial sync")
+			return
+		}
+		fatal("Initial sync failed", "error", err)
+	}
+	slog.Info("Initial sync completed successfully")
+
+	if config.RunOnce {
+		slog.Info("Run-once mode: exiting after initial sync")
+		return
+	}
+
+	// Node changes trigger a sync when watch mode is enabled, once they have
+	// settled for MinWriteInterval
+	trigger := make(chan struct{}, 1)
+	debounce := newDebouncer(config.MinWriteInterval)
+	stopWatch := func() {}
+	startWatch := func() {
+		if !config.WatchMode {
+			return
+		}
+		var watchCtx context.Context
+		watchCtx, stopWatch = context.WithCancel(ctx)
+		slog.Info("Starting node watch")
+		go watchNodes(watchCtx, clientset, config, trigger)
+	}
+	startWatch()
+	defer func() { stopWatch() }()
+
+	// SIGHUP reloads the configuration without a restart
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	// Set up periodic sync, which also acts as the fallback reconcile in watch mode
+	timer := time.NewTimer(nextSyncDelay(config))
+	defer timer.Stop()
+
+	slog.Info("Starting periodic sync", "interval", config.SyncInterval, "jitter", config.SyncJitter)
+	syncState.setRunning()
+	syncLoopRunning.Store(true)
+	defer syncLoopRunning.Store(false)
+	notifier := newNotifier(config)
+
+	// Consecutive failed syncs, which shorten the wait before the next attempt
+	failures := 0
+	runSync := func() error {
+		err := syncDNSRecords(ctx, ipProvider, providers, config)
+		notifier.observe(ctx, err)
+		if err == nil {
+			failures = 0
+			return nil
+		}
+
+		failures++
+		delay := failureRetryDelay(config, failures)
+		slog.Error("Sync failed", "error", err, "retry_in", delay)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(delay)
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Syncs run on this goroutine, so any in-progress sync has finished here
+			slog.Info("Shutting down: termination signal received, stopping sync loop")
+			return
+		case <-timer.C:
+			debounce.cancel()
+			if runSync() == nil {
+				timer.Reset(nextSyncDelay(config))
+			}
+		case <-trigger:
+			if debounce.event() {
+				runSync()
+			} else {
+				slog.Debug("Delaying triggered sync until node changes settle", "min_write_interval", config.MinWriteInterval)
+			}
+		case <-debounce.C():
+			debounce.fired()
+			runSync()
+		case request := <-syncRequests:
+			slog.Info("Sync requested over HTTP")
+			debounce.cancel()
+			err := runSync()
+			request.reply <- newSyncResponse(lastSyncSummary, config.DryRun, err)
+		case <-reload:
+			slog.Info("Received SIGHUP, reloading configuration")
+			newConfig, newProviders, err := reloadConfig(ctx)
+			if err != nil {
+				slog.Error("Configuration reload failed, keeping the running configuration", "error", err)
+				continue
+			}
+
+			if (newConfig.NodesFile == "") != (config.NodesFile == "") {
+				slog.Error("NODES_FILE cannot be set or unset by a reload, keeping the running configuration")
+				continue
+			}
+
+			changes, needRestart := configChanges(config, newConfig)
+			if len(changes) == 0 {
+				slog.Info("Configuration unchanged")
+				continue
+			}
+			slog.Info("Configuration reloaded", "changes", changes)
+			if len(needRestart) > 0 {
+				slog.Warn("Some changed settings only take effect after a restart", "settings", needRestart)
+			}
+
+			stopWatch()
+			config, providers = newConfig, newProviders
+			effectiveConfig.Store(config)
+			ipProvider = newIPProvider(clientset, config)
+			notifier = notifier.reconfigured(config)
+			debounce.cancel()
+			debounce.interval = config.MinWriteInterval
+			startWatch()
+			runSync()
 		}
 	}
 }
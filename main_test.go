@@ -1,9 +1,24 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 )
 
 func TestParseIPAddresses(t *testing.T) {
@@ -63,6 +78,62 @@ func TestParseIPAddresses(t *testing.T) {
 			ipv4Count: 0,
 			ipv6Count: 0,
 		},
+		{
+			name:      "IPv4 with prefix",
+			input:     "1.2.3.4/32",
+			expected:  1,
+			ipv4Count: 1,
+			ipv6Count: 0,
+		},
+		{
+			name:      "IPv4 with port",
+			input:     "1.2.3.4:6443",
+			expected:  1,
+			ipv4Count: 1,
+			ipv6Count: 0,
+		},
+		{
+			name:      "IPv6 with prefix",
+			input:     "2001:db8::1/128",
+			expected:  1,
+			ipv4Count: 0,
+			ipv6Count: 1,
+		},
+		{
+			name:      "Invalid prefix",
+			input:     "1.2.3.4/99",
+			expected:  0,
+			ipv4Count: 0,
+			ipv6Count: 0,
+		},
+		{
+			name:      "Trailing comma",
+			input:     "1.2.3.4,",
+			expected:  1,
+			ipv4Count: 1,
+			ipv6Count: 0,
+		},
+		{
+			name:      "Doubled and leading commas",
+			input:     ",1.2.3.4,,5.6.7.8,",
+			expected:  2,
+			ipv4Count: 2,
+			ipv6Count: 0,
+		},
+		{
+			name:      "Mixed whitespace",
+			input:     " 1.2.3.4 ,\t2001:db8::1\n, ",
+			expected:  2,
+			ipv4Count: 1,
+			ipv6Count: 1,
+		},
+		{
+			name:      "Only separators",
+			input:     " , ,, ",
+			expected:  0,
+			ipv4Count: 0,
+			ipv6Count: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -98,6 +169,34 @@ func TestParseIPAddresses(t *testing.T) {
 	}
 }
 
+func TestParseIPValueStripsSuffixes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.2.3.4", "1.2.3.4"},
+		{"1.2.3.4/32", "1.2.3.4"},
+		{"1.2.3.4:6443", "1.2.3.4"},
+		{"2001:db8::1/64", "2001:db8::1"},
+		{"[2001:db8::1]:6443", ""},
+		{"example.com:6443", ""},
+		{"2001:db8::1%eth0", "2001:db8::1"},
+		{"fe80::1%eth0", "fe80::1"},
+		{"2001:db8::1%eth0/64", "2001:db8::1"},
+		{"%eth0", ""},
+	}
+
+	for _, tt := range tests {
+		ip, got := parseIPValue(tt.input)
+		if got != tt.want {
+			t.Errorf("parseIPValue(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if (ip == nil) != (tt.want == "") {
+			t.Errorf("parseIPValue(%q) ip = %v, want valid=%v", tt.input, ip, tt.want != "")
+		}
+	}
+}
+
 func TestIPAddressClassification(t *testing.T) {
 	tests := []struct {
 		ip     string
@@ -159,7 +258,7 @@ func TestConfigValidation(t *testing.T) {
 			if !strings.HasSuffix(recordName, ".") {
 				recordName += "."
 			}
-			
+
 			if recordName != tc.expected {
 				t.Errorf("Expected %s, got %s", tc.expected, recordName)
 			}
@@ -245,7 +344,7 @@ func TestKubernetesPermissionErrors(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			isForbidden := strings.Contains(tt.errorMsg, "forbidden")
 			if isForbidden != tt.shouldMatch {
-				t.Errorf("Error classification mismatch for '%s': expected forbidden=%v, got forbidden=%v", 
+				t.Errorf("Error classification mismatch for '%s': expected forbidden=%v, got forbidden=%v",
 					tt.errorMsg, tt.shouldMatch, isForbidden)
 			}
 		})
@@ -285,10 +384,1698 @@ func TestNodeSelectorConfiguration(t *testing.T) {
 			config := &Config{
 				NodeSelector: tt.nodeSelector,
 			}
-			
+
 			if config.NodeSelector != tt.expected {
 				t.Errorf("NodeSelector = %s, want %s", config.NodeSelector, tt.expected)
 			}
 		})
 	}
 }
+
+func TestParseDNSRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Single record",
+			input:    "cluster.example.com",
+			expected: []string{"cluster.example.com."},
+		},
+		{
+			name:     "Multiple records",
+			input:    "cluster.example.com,ingress.example.com.",
+			expected: []string{"cluster.example.com.", "ingress.example.com."},
+		},
+		{
+			name:     "Empty entries and spaces",
+			input:    " cluster.example.com, ,ingress.example.com,",
+			expected: []string{"cluster.example.com.", "ingress.example.com."},
+		},
+		{
+			name:     "Duplicate records",
+			input:    "cluster.example.com,cluster.example.com.",
+			expected: []string{"cluster.example.com."},
+		},
+		{
+			name:     "Empty string",
+			input:    "",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseDNSRecords(tt.input)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("parseDNSRecords(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvBool(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		defaultValue bool
+		expected     bool
+	}{
+		{name: "Unset uses default", value: "", defaultValue: true, expected: true},
+		{name: "True", value: "true", defaultValue: false, expected: true},
+		{name: "Numeric true", value: "1", defaultValue: false, expected: true},
+		{name: "False", value: "false", defaultValue: true, expected: false},
+		{name: "Invalid uses default", value: "maybe", defaultValue: false, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_BOOL", tt.value)
+			if result := getEnvBool("TEST_BOOL", tt.defaultValue); result != tt.expected {
+				t.Errorf("getEnvBool(%q) = %v, want %v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+// setRequiredEnv sets the environment variables loadConfig requires.
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("POWERDNS_URL", "http://powerdns:8081")
+	t.Setenv("POWERDNS_API_KEY", "secret")
+	t.Setenv("DNS_ZONE", "example.com")
+	t.Setenv("DNS_RECORD", "cluster.example.com")
+}
+
+func TestLoadConfigNodeSelectorValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodeSelector string
+		expectError  bool
+	}{
+		{name: "Empty selector", nodeSelector: "", expectError: false},
+		{name: "Equality selector", nodeSelector: "role=edge", expectError: false},
+		{name: "Set selector", nodeSelector: "role in (edge,ingress),!excluded", expectError: false},
+		{name: "Invalid selector", nodeSelector: "role in edge", expectError: true},
+		{name: "Invalid label key", nodeSelector: "-role=edge", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("NODE_SELECTOR", tt.nodeSelector)
+
+			_, err := loadConfig()
+			if (err != nil) != tt.expectError {
+				t.Errorf("loadConfig() with NODE_SELECTOR=%q error = %v, expectError %v", tt.nodeSelector, err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestNodeExclusionReason(t *testing.T) {
+	readyCondition := func(status corev1.ConditionStatus) []corev1.NodeCondition {
+		return []corev1.NodeCondition{
+			{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+			{Type: corev1.NodeReady, Status: status},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		node     corev1.Node
+		excluded bool
+	}{
+		{
+			name:     "Ready and schedulable",
+			node:     corev1.Node{Status: corev1.NodeStatus{Conditions: readyCondition(corev1.ConditionTrue)}},
+			excluded: false,
+		},
+		{
+			name: "Cordoned",
+			node: corev1.Node{
+				Spec:   corev1.NodeSpec{Unschedulable: true},
+				Status: corev1.NodeStatus{Conditions: readyCondition(corev1.ConditionTrue)},
+			},
+			excluded: true,
+		},
+		{
+			name:     "NotReady",
+			node:     corev1.Node{Status: corev1.NodeStatus{Conditions: readyCondition(corev1.ConditionFalse)}},
+			excluded: true,
+		},
+		{
+			name:     "Unknown readiness",
+			node:     corev1.Node{Status: corev1.NodeStatus{Conditions: readyCondition(corev1.ConditionUnknown)}},
+			excluded: true,
+		},
+		{
+			name:     "No Ready condition",
+			node:     corev1.Node{},
+			excluded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := nodeExclusionReason(&tt.node)
+			if (reason != "") != tt.excluded {
+				t.Errorf("nodeExclusionReason() = %q, want excluded=%v", reason, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestNodeIPSources(t *testing.T) {
+	node := &corev1.Node{
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
+				{Type: corev1.NodeExternalIP, Address: "203.0.113.5"},
+				{Type: corev1.NodeExternalIP, Address: "2001:db8::5"},
+				{Type: corev1.NodeHostName, Address: "node1"},
+			},
+		},
+	}
+	node.Name = "node1"
+	node.Annotations = map[string]string{ExternalIPAnnotation: "198.51.100.7"}
+
+	statusIPs := nodeStatusIPs(node, []string{"ExternalIP"})
+	if len(statusIPs) != 2 || statusIPs[0].String != "203.0.113.5" || statusIPs[1].String != "2001:db8::5" {
+		t.Errorf("nodeStatusIPs() = %v, want only the ExternalIP addresses", statusIPs)
+	}
+
+	statusIPs = nodeStatusIPs(node, []string{"InternalIP", "ExternalIP"})
+	if len(statusIPs) != 1 || statusIPs[0].String != "10.0.0.5" {
+		t.Errorf("nodeStatusIPs() preferring InternalIP = %v, want only the InternalIP address", statusIPs)
+	}
+
+	internalOnly := &corev1.Node{Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.6"}}}}
+	statusIPs = nodeStatusIPs(internalOnly, []string{"ExternalIP", "InternalIP"})
+	if len(statusIPs) != 1 || statusIPs[0].String != "10.0.0.6" {
+		t.Errorf("nodeStatusIPs() falling back to InternalIP = %v, want 10.0.0.6", statusIPs)
+	}
+
+	annotationIPs := nodeAnnotationIPs(node, []string{ExternalIPAnnotation}, DefaultSeparator)
+	if len(annotationIPs) != 1 || annotationIPs[0].String != "198.51.100.7" {
+		t.Errorf("nodeAnnotationIPs() = %v, want the annotation address", annotationIPs)
+	}
+
+	if ips := nodeAnnotationIPs(node, []string{"example.com/public-ip"}, DefaultSeparator); len(ips) != 0 {
+		t.Errorf("nodeAnnotationIPs() with custom key = %v, want none", ips)
+	}
+
+	if ips := nodeStatusIPs(&corev1.Node{}, []string{"ExternalIP"}); len(ips) != 0 {
+		t.Errorf("nodeStatusIPs() on node without addresses = %v, want none", ips)
+	}
+}
+
+func TestLoadConfigIPSource(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    string
+		expectError bool
+	}{
+		{value: "", expected: IPSourceAnnotation},
+		{value: "status", expected: IPSourceStatus},
+		{value: "Both", expected: IPSourceBoth},
+		{value: "everything", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("IP_SOURCE", tt.value)
+
+			config, err := loadConfig()
+			if (err != nil) != tt.expectError {
+				t.Fatalf("loadConfig() error = %v, expectError %v", err, tt.expectError)
+			}
+			if err == nil && config.IPSource != tt.expected {
+				t.Errorf("IPSource = %s, want %s", config.IPSource, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecordValuesEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        []string
+		b        []string
+		expected bool
+	}{
+		{name: "Identical", a: []string{"1.2.3.4", "5.6.7.8"}, b: []string{"1.2.3.4", "5.6.7.8"}, expected: true},
+		{name: "Different order", a: []string{"5.6.7.8", "1.2.3.4"}, b: []string{"1.2.3.4", "5.6.7.8"}, expected: true},
+		{name: "Different values", a: []string{"1.2.3.4"}, b: []string{"1.2.3.5"}, expected: false},
+		{name: "Different length", a: []string{"1.2.3.4"}, b: []string{"1.2.3.4", "5.6.7.8"}, expected: false},
+		{name: "Both empty", a: nil, b: []string{}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := recordValuesEqual(tt.a, tt.b); result != tt.expected {
+				t.Errorf("recordValuesEqual(%v, %v) = %v, want %v", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecordTTL(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		recordType RecordType
+		expected   int
+	}{
+		{name: "Global TTL for A", env: map[string]string{"DNS_TTL": "5m"}, recordType: RecordTypeA, expected: 300},
+		{name: "A override", env: map[string]string{"DNS_TTL": "5m", "DNS_TTL_A": "60s"}, recordType: RecordTypeA, expected: 60},
+		{name: "AAAA override", env: map[string]string{"DNS_TTL_AAAA": "1h"}, recordType: RecordTypeAAAA, expected: 3600},
+		{name: "A override does not affect AAAA", env: map[string]string{"DNS_TTL_A": "60s"}, recordType: RecordTypeAAAA, expected: DefaultTTL},
+		{name: "Invalid override falls back", env: map[string]string{"DNS_TTL": "10m", "DNS_TTL_A": "soon"}, recordType: RecordTypeA, expected: 600},
+		{name: "Other types use global TTL", env: map[string]string{"DNS_TTL_A": "60s"}, recordType: RecordTypePTR, expected: DefaultTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			config, err := loadConfig()
+			if err != nil {
+				t.Fatalf("loadConfig() error = %v", err)
+			}
+			if result := recordTTL(config, tt.recordType); result != tt.expected {
+				t.Errorf("recordTTL(%s) = %d, want %d", tt.recordType, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecordInZone(t *testing.T) {
+	tests := []struct {
+		record   string
+		zone     string
+		expected bool
+	}{
+		{record: "cluster.example.com.", zone: "example.com.", expected: true},
+		{record: "example.com.", zone: "example.com.", expected: true},
+		{record: "a.b.example.com", zone: "example.com", expected: true},
+		{record: "Cluster.Example.COM.", zone: "example.com.", expected: true},
+		{record: "foo.other.com.", zone: "example.com.", expected: false},
+		{record: "notexample.com.", zone: "example.com.", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.record+"_in_"+tt.zone, func(t *testing.T) {
+			if result := recordInZone(tt.record, tt.zone); result != tt.expected {
+				t.Errorf("recordInZone(%s, %s) = %v, want %v", tt.record, tt.zone, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsRecordOutsideZone(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DNS_RECORD", "cluster.example.com,foo.other.com")
+
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should fail when a DNS_RECORD is outside DNS_ZONE")
+	}
+}
+
+func TestLoadConfigAPIKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "api-key")
+	if err := os.WriteFile(keyFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("POWERDNS_API_KEY_FILE", keyFile)
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.PowerDNSAPIKey != "from-file" {
+		t.Errorf("PowerDNSAPIKey = %q, want %q", config.PowerDNSAPIKey, "from-file")
+	}
+
+	t.Setenv("POWERDNS_API_KEY", "")
+	t.Setenv("POWERDNS_API_KEY_FILE", filepath.Join(dir, "missing"))
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should fail when POWERDNS_API_KEY_FILE is unreadable")
+	}
+}
+
+func TestLoadConfigCNAME(t *testing.T) {
+	tests := []struct {
+		name    string
+		cname   string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid alias", cname: "alias.example.com", want: "alias.example.com."},
+		{name: "same as record", cname: "Cluster.example.com.", wantErr: true},
+		{name: "outside zone", cname: "alias.other.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("DNS_CNAME", tt.cname)
+
+			config, err := loadConfig()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("loadConfig() should fail for DNS_CNAME %q", tt.cname)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig() error = %v", err)
+			}
+			if config.CNAME != tt.want {
+				t.Errorf("CNAME = %q, want %q", config.CNAME, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterIPAddressesExcludePrivate(t *testing.T) {
+	ips, _ := parseIPAddresses("10.0.0.1,192.168.1.10,172.16.5.4,127.0.0.1,169.254.1.1,203.0.113.5,fd00::1,fe80::1,::1,2001:db8::1")
+
+	unfiltered := filterIPAddresses(ips, &Config{})
+	if len(unfiltered) != len(ips) {
+		t.Errorf("filterIPAddresses() without EXCLUDE_PRIVATE dropped addresses: %v", unfiltered)
+	}
+
+	filtered := filterIPAddresses(ips, &Config{ExcludePrivate: true})
+	result := strings.Join(ipStrings(filtered), ",")
+	if result != "203.0.113.5,2001:db8::1" {
+		t.Errorf("filterIPAddresses() with EXCLUDE_PRIVATE = %s, want 203.0.113.5,2001:db8::1", result)
+	}
+}
+
+func TestFilterIPAddressesLinkLocal(t *testing.T) {
+	ips, _ := parseIPAddresses("fe80::1%eth0,2001:db8::1%eth0,169.254.1.1,203.0.113.5,ff02::1")
+
+	filtered := filterIPAddresses(ips, &Config{ExcludeLinkLocal: true})
+	if got := strings.Join(ipStrings(filtered), ","); got != "2001:db8::1,203.0.113.5" {
+		t.Errorf("filterIPAddresses() with EXCLUDE_LINK_LOCAL = %s, want 2001:db8::1,203.0.113.5", got)
+	}
+
+	unfiltered := filterIPAddresses(ips, &Config{})
+	if got := strings.Join(ipStrings(unfiltered), ","); got != "fe80::1,2001:db8::1,169.254.1.1,203.0.113.5,ff02::1" {
+		t.Errorf("filterIPAddresses() without EXCLUDE_LINK_LOCAL = %s", got)
+	}
+}
+
+func TestFilterIPAddressesCIDRs(t *testing.T) {
+	ips, _ := parseIPAddresses("203.0.113.5,203.0.113.200,198.51.100.7,2001:db8::1,2001:db9::1")
+
+	include, err := parseCIDRs("203.0.113.0/24, 2001:db8::/32")
+	if err != nil {
+		t.Fatalf("parseCIDRs() error = %v", err)
+	}
+	exclude, err := parseCIDRs("203.0.113.128/25")
+	if err != nil {
+		t.Fatalf("parseCIDRs() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		expected string
+	}{
+		{name: "Include only", config: &Config{IncludeCIDRs: include}, expected: "203.0.113.5,203.0.113.200,2001:db8::1"},
+		{name: "Exclude only", config: &Config{ExcludeCIDRs: exclude}, expected: "203.0.113.5,198.51.100.7,2001:db8::1,2001:db9::1"},
+		{name: "Include and exclude", config: &Config{IncludeCIDRs: include, ExcludeCIDRs: exclude}, expected: "203.0.113.5,2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := strings.Join(ipStrings(filterIPAddresses(ips, tt.config)), ",")
+			if result != tt.expected {
+				t.Errorf("filterIPAddresses() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsInvalidCIDRs(t *testing.T) {
+	for _, key := range []string{"INCLUDE_CIDRS", "EXCLUDE_CIDRS"} {
+		t.Run(key, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv(key, "203.0.113.0/24,not-a-cidr")
+
+			if _, err := loadConfig(); err == nil {
+				t.Errorf("loadConfig() should fail with invalid %s", key)
+			}
+		})
+	}
+}
+
+func TestSelectByPriority(t *testing.T) {
+	candidate := func(ip, node string, priority int) IPAddress {
+		parsed, _ := parseIPAddresses(ip)
+		parsed[0].Node = node
+		parsed[0].Priority = priority
+		return parsed[0]
+	}
+	candidates := []IPAddress{
+		candidate("203.0.113.30", "low", 0),
+		candidate("2001:db8::1", "high", 10),
+		candidate("203.0.113.20", "mid", 5),
+		candidate("203.0.113.10", "tie", 5),
+	}
+
+	kept, dropped := selectByPriority(candidates, 2)
+
+	var got []string
+	for _, c := range kept {
+		got = append(got, c.String)
+	}
+	if want := "2001:db8::1,203.0.113.10"; strings.Join(got, ",") != want {
+		t.Errorf("kept = %v, want %s", got, want)
+	}
+	if len(dropped) != 2 || dropped[0].Node != "mid" || dropped[1].Node != "low" {
+		t.Errorf("dropped = %+v, want mid then low", dropped)
+	}
+}
+
+func TestNodePriority(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		want        int
+	}{
+		{nil, 0},
+		{map[string]string{PriorityAnnotation: "100"}, 100},
+		{map[string]string{PriorityAnnotation: " -5 "}, -5},
+		{map[string]string{PriorityAnnotation: "high"}, 0},
+	}
+
+	for _, tt := range tests {
+		node := &corev1.Node{}
+		node.Annotations = tt.annotations
+		if got := nodePriority(node); got != tt.want {
+			t.Errorf("nodePriority(%v) = %d, want %d", tt.annotations, got, tt.want)
+		}
+	}
+}
+
+func TestNodeOptedOut(t *testing.T) {
+	tests := []struct {
+		value string
+		set   bool
+		want  bool
+	}{
+		{set: false, want: false},
+		{value: "true", set: true, want: true},
+		{value: "false", set: true, want: false},
+		{value: "maybe", set: true, want: false},
+	}
+
+	for _, tt := range tests {
+		node := &corev1.Node{}
+		if tt.set {
+			node.Annotations = map[string]string{ExcludeAnnotation: tt.value}
+		}
+		if got := nodeOptedOut(node, ExcludeAnnotation); got != tt.want {
+			t.Errorf("nodeOptedOut(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNextSyncDelay(t *testing.T) {
+	config := &Config{SyncInterval: 30 * time.Second}
+	if got := nextSyncDelay(config); got != config.SyncInterval {
+		t.Errorf("nextSyncDelay() without jitter = %v, want %v", got, config.SyncInterval)
+	}
+
+	config.SyncJitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := nextSyncDelay(config)
+		if got < 30*time.Second || got > 45*time.Second {
+			t.Fatalf("nextSyncDelay() with 0.5 jitter = %v, want within [30s, 45s]", got)
+		}
+	}
+}
+
+func TestFailureRetryDelay(t *testing.T) {
+	config := &Config{SyncInterval: 30 * time.Second, FailureRetry: 5 * time.Second}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{4, 30 * time.Second},
+		{50, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := failureRetryDelay(config, tt.failures); got != tt.want {
+			t.Errorf("failureRetryDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	config.FailureRetry = time.Minute
+	if got := failureRetryDelay(config, 1); got != config.SyncInterval {
+		t.Errorf("failureRetryDelay() above sync interval = %v, want %v", got, config.SyncInterval)
+	}
+}
+
+func TestLoadConfigK8sTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", DefaultK8sTimeout},
+		{"30s", 30 * time.Second},
+		{"0s", DefaultK8sTimeout},
+		{"soon", DefaultK8sTimeout},
+	}
+
+	for _, tt := range tests {
+		setRequiredEnv(t)
+		t.Setenv("K8S_TIMEOUT", tt.value)
+
+		config, err := loadConfig()
+		if err != nil {
+			t.Fatalf("loadConfig() error = %v", err)
+		}
+		if config.K8sTimeout != tt.want {
+			t.Errorf("K8S_TIMEOUT=%q: K8sTimeout = %v, want %v", tt.value, config.K8sTimeout, tt.want)
+		}
+	}
+}
+
+func TestSortIPAddressesNumerically(t *testing.T) {
+	ips, _ := parseIPAddresses("2001:db8::10,10.0.0.2,9.0.0.1,2001:db8::9,192.168.1.1")
+	sortIPAddresses(ips)
+
+	want := "9.0.0.1,10.0.0.2,192.168.1.1,2001:db8::9,2001:db8::10"
+	if got := strings.Join(ipStrings(ips), ","); got != want {
+		t.Errorf("sortIPAddresses() = %s, want %s", got, want)
+	}
+}
+
+func TestLoadConfigManageRecordTypes(t *testing.T) {
+	setRequiredEnv(t)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if !config.ManageA || !config.ManageAAAA {
+		t.Errorf("ManageA = %v, ManageAAAA = %v, want both true by default", config.ManageA, config.ManageAAAA)
+	}
+
+	t.Setenv("MANAGE_AAAA", "false")
+	if config, err = loadConfig(); err != nil || config.ManageAAAA {
+		t.Errorf("MANAGE_AAAA=false: ManageAAAA = %v, error = %v", config.ManageAAAA, err)
+	}
+
+	t.Setenv("MANAGE_A", "false")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should fail when both MANAGE_A and MANAGE_AAAA are false")
+	}
+}
+
+func TestNodeAnnotationIPsKeyOrder(t *testing.T) {
+	node := &corev1.Node{}
+	node.Name = "node1"
+	node.Annotations = map[string]string{
+		"example.com/public-ip": "",
+		ExternalIPAnnotation:    "198.51.100.7",
+		"example.com/new-ip":    "203.0.113.9",
+	}
+
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"example.com/new-ip", ExternalIPAnnotation}, "203.0.113.9"},
+		{[]string{ExternalIPAnnotation, "example.com/new-ip"}, "198.51.100.7"},
+		{[]string{"example.com/public-ip", "example.com/missing", ExternalIPAnnotation}, "198.51.100.7"},
+		{[]string{"example.com/public-ip", "example.com/missing"}, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(ipStrings(nodeAnnotationIPs(node, tt.keys, DefaultSeparator)), ","); got != tt.want {
+			t.Errorf("nodeAnnotationIPs(%v) = %s, want %s", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigAnnotationKeys(t *testing.T) {
+	setRequiredEnv(t)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if strings.Join(config.AnnotationKeys, ",") != ExternalIPAnnotation {
+		t.Errorf("AnnotationKeys = %v, want the default %s", config.AnnotationKeys, ExternalIPAnnotation)
+	}
+
+	t.Setenv("ANNOTATION_KEY", "example.com/new-ip, k3s.io/external-ip,")
+	if config, err = loadConfig(); err != nil || strings.Join(config.AnnotationKeys, ",") != "example.com/new-ip,k3s.io/external-ip" {
+		t.Errorf("AnnotationKeys = %v, error = %v, want both keys in order", config.AnnotationKeys, err)
+	}
+}
+
+func TestKeepExistingValues(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeA, 300, "192.0.2.1", "192.0.2.2")
+	provider := mock.target(t)
+	config := &Config{NoDelete: true}
+
+	tests := []struct {
+		current []string
+		want    string
+	}{
+		{[]string{"192.0.2.1", "192.0.2.2"}, "192.0.2.1,192.0.2.2"},
+		{[]string{"192.0.2.3"}, "192.0.2.3,192.0.2.1,192.0.2.2"},
+		{nil, "192.0.2.1,192.0.2.2"},
+	}
+	for _, tt := range tests {
+		got, err := keepExistingValues(context.Background(), provider, config, "example.com.", "www.example.com.", RecordTypeA, tt.current)
+		if err != nil {
+			t.Fatalf("keepExistingValues() error = %v", err)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("keepExistingValues(%v) = %v, want %s", tt.current, got, tt.want)
+		}
+	}
+}
+
+// newUpdateTestConfig returns the configuration of the updateDNSRecords
+// tests: a single record, both address families and the default TTL.
+func newUpdateTestConfig() *Config {
+	config := newFetchTestConfig()
+	config.DNSZone = "example.com."
+	config.DNSRecords = []string{"www.example.com."}
+	config.TTL = DefaultTTL
+	config.ManageA = true
+	config.ManageAAAA = true
+	return config
+}
+
+func TestUpdateDNSRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[RecordType][]string
+		ips      string
+		want     []string
+		wantA    string
+		wantAAAA string
+	}{
+		{
+			name:     "Create both families",
+			ips:      "192.0.2.1,2001:db8::1",
+			want:     []string{"REPLACE A www.example.com. 192.0.2.1", "REPLACE AAAA www.example.com. 2001:db8::1"},
+			wantA:    "192.0.2.1",
+			wantAAAA: "2001:db8::1",
+		},
+		{
+			name:     "Update changed IPs",
+			existing: map[RecordType][]string{RecordTypeA: {"192.0.2.1"}, RecordTypeAAAA: {"2001:db8::1"}},
+			ips:      "192.0.2.1,192.0.2.2,2001:db8::1",
+			want:     []string{"REPLACE A www.example.com. 192.0.2.1,192.0.2.2"},
+			wantA:    "192.0.2.1,192.0.2.2",
+			wantAAAA: "2001:db8::1",
+		},
+		{
+			name:     "Skip unchanged records",
+			existing: map[RecordType][]string{RecordTypeA: {"192.0.2.2", "192.0.2.1"}},
+			ips:      "192.0.2.1,192.0.2.2",
+			wantA:    "192.0.2.2,192.0.2.1",
+		},
+		{
+			name:     "Delete the family without IPs",
+			existing: map[RecordType][]string{RecordTypeA: {"192.0.2.1"}, RecordTypeAAAA: {"2001:db8::1"}},
+			ips:      "192.0.2.1",
+			want:     []string{"DELETE AAAA www.example.com."},
+			wantA:    "192.0.2.1",
+		},
+		{
+			name:     "Clean up when no IPs remain",
+			existing: map[RecordType][]string{RecordTypeA: {"192.0.2.1"}, RecordTypeAAAA: {"2001:db8::1"}},
+			want:     []string{"DELETE A www.example.com.", "DELETE AAAA www.example.com."},
+		},
+		{
+			name: "Nothing to clean up",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockPowerDNS(t, "example.com.")
+			for recordType, values := range tt.existing {
+				mock.set("www.example.com.", recordType, DefaultTTL, values...)
+			}
+			ips, _ := parseIPAddresses(tt.ips)
+
+			if _, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, newUpdateTestConfig(), ips); err != nil {
+				t.Fatalf("updateDNSRecords() error = %v", err)
+			}
+
+			if got := mock.takeChanges(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+			if got := strings.Join(mock.values("www.example.com.", RecordTypeA), ","); got != tt.wantA {
+				t.Errorf("A record = %s, want %s", got, tt.wantA)
+			}
+			if got := strings.Join(mock.values("www.example.com.", RecordTypeAAAA), ","); got != tt.wantAAAA {
+				t.Errorf("AAAA record = %s, want %s", got, tt.wantAAAA)
+			}
+		})
+	}
+}
+
+func TestUpdateDNSRecordsTTLChange(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeA, 60, "192.0.2.1")
+	ips, _ := parseIPAddresses("192.0.2.1")
+
+	summary, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, newUpdateTestConfig(), ips)
+	if err != nil {
+		t.Fatalf("updateDNSRecords() error = %v", err)
+	}
+	if got := mock.takeChanges(); strings.Join(got, ",") != "REPLACE A www.example.com. 192.0.2.1" {
+		t.Errorf("changes = %q, want the A record rewritten with the new TTL", got)
+	}
+	if counts := summary.Types[RecordTypeA]; counts == nil || counts.Updated != 1 {
+		t.Errorf("summary = %s, want 1 updated A record", summary)
+	}
+}
+
+func TestUpdateDNSRecordsDryRun(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeAAAA, DefaultTTL, "2001:db8::1")
+	ips, _ := parseIPAddresses("192.0.2.1")
+
+	config := newUpdateTestConfig()
+	config.DryRun = true
+	summary, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, config, ips)
+	if err != nil {
+		t.Fatalf("updateDNSRecords() error = %v", err)
+	}
+	if got := mock.takeChanges(); len(got) != 0 {
+		t.Errorf("dry run sent changes %q", got)
+	}
+	want := "A: 1 created, 0 updated, 0 unchanged, 0 deleted; AAAA: 0 created, 0 updated, 0 unchanged, 1 deleted"
+	if summary.String() != want {
+		t.Errorf("summary = %s, want %s", summary, want)
+	}
+}
+
+func TestUpdateDNSRecordsMultipleTargets(t *testing.T) {
+	healthy := newMockPowerDNS(t, "example.com.")
+	wrongZone := newMockPowerDNS(t, "example.org.")
+	ips, _ := parseIPAddresses("192.0.2.1")
+	config := newUpdateTestConfig()
+
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{wrongZone.target(t), healthy.target(t)}, config, ips); err != nil {
+		t.Fatalf("updateDNSRecords() should succeed while one target works, error = %v", err)
+	}
+	if got := strings.Join(healthy.values("www.example.com.", RecordTypeA), ","); got != "192.0.2.1" {
+		t.Errorf("A record on the healthy target = %s, want 192.0.2.1", got)
+	}
+
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{wrongZone.target(t)}, config, ips); err == nil {
+		t.Error("updateDNSRecords() should fail when no target works")
+	}
+}
+
+func TestVerifyPowerDNSTargetsMock(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	config := newUpdateTestConfig()
+
+	if err := verifyPowerDNSTargets(context.Background(), []*PowerDNSTarget{mock.target(t)}, config); err != nil {
+		t.Errorf("verifyPowerDNSTargets() error = %v", err)
+	}
+
+	config.DNSZone = "example.org."
+	if err := verifyPowerDNSTargets(context.Background(), []*PowerDNSTarget{mock.target(t)}, config); err == nil {
+		t.Error("verifyPowerDNSTargets() should fail for a zone the server does not serve")
+	}
+}
+
+func TestVerifyPowerDNSTargetsCreateZone(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.absent = true
+	config := newUpdateTestConfig()
+
+	if err := verifyPowerDNSTargets(context.Background(), []*PowerDNSTarget{mock.target(t)}, config); err == nil {
+		t.Fatal("verifyPowerDNSTargets() should fail for a missing zone without CREATE_ZONE")
+	}
+
+	config.CreateZone = true
+	config.ZoneKind = "Master"
+	config.ZoneNameservers = []string{"ns1.example.com.", "ns2.example.com."}
+	if err := verifyPowerDNSTargets(context.Background(), []*PowerDNSTarget{mock.target(t)}, config); err != nil {
+		t.Fatalf("verifyPowerDNSTargets() error = %v", err)
+	}
+	want := "CREATE Master example.com. ns1.example.com.,ns2.example.com."
+	if got := strings.Join(mock.takeChanges(), "; "); got != want {
+		t.Errorf("changes = %s, want %s", got, want)
+	}
+
+	// The zone now exists, so it is not created again
+	if err := verifyPowerDNSTargets(context.Background(), []*PowerDNSTarget{mock.target(t)}, config); err != nil {
+		t.Fatalf("verifyPowerDNSTargets() error = %v", err)
+	}
+	if got := mock.takeChanges(); len(got) != 0 {
+		t.Errorf("changes = %v, want none", got)
+	}
+}
+
+func TestLoadConfigCreateZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantKind string
+		wantErr  bool
+	}{
+		{name: "Disabled", env: map[string]string{}, wantKind: "Native"},
+		{name: "Nameservers required", env: map[string]string{"CREATE_ZONE": "true"}, wantErr: true},
+		{name: "Native", env: map[string]string{"CREATE_ZONE": "true", "ZONE_NAMESERVERS": "ns1.example.com"}, wantKind: "Native"},
+		{name: "Master", env: map[string]string{"CREATE_ZONE": "true", "ZONE_NAMESERVERS": "ns1.example.com", "ZONE_KIND": "master"}, wantKind: "Master"},
+		{name: "Invalid kind", env: map[string]string{"ZONE_KIND": "Slave"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			config, err := loadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && config.ZoneKind != tt.wantKind {
+				t.Errorf("ZoneKind = %q, want %q", config.ZoneKind, tt.wantKind)
+			}
+		})
+	}
+}
+
+// newTestNode returns a node with the given annotations and labels.
+func newTestNode(name string, annotations, labels map[string]string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations, Labels: labels}}
+}
+
+// newFetchTestConfig returns the defaults fetchExternalIPs runs with.
+func newFetchTestConfig() *Config {
+	return &Config{
+		IPSource:          IPSourceAnnotation,
+		AnnotationKeys:    []string{ExternalIPAnnotation},
+		ExcludeAnnotation: ExcludeAnnotation,
+		Separator:         DefaultSeparator,
+		K8sTimeout:        DefaultK8sTimeout,
+	}
+}
+
+func TestFetchExternalIPs(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "203.0.113.20,2001:db8::2"}, nil),
+		newTestNode("node-b", map[string]string{ExternalIPAnnotation: "198.51.100.7, 203.0.113.20"}, nil),
+		newTestNode("node-c", nil, nil),
+		newTestNode("node-d", map[string]string{ExternalIPAnnotation: ""}, nil),
+		newTestNode("node-e", map[string]string{ExternalIPAnnotation: "not-an-ip,192.0.2.300"}, nil),
+		newTestNode("node-f", map[string]string{ExternalIPAnnotation: `["192.0.2.1",`}, nil),
+		newTestNode("node-g", map[string]string{ExternalIPAnnotation: "2001:db8::1,192.0.2.9"}, nil),
+	)
+
+	ips, err := fetchExternalIPs(context.Background(), clientset, newFetchTestConfig())
+	if err != nil {
+		t.Fatalf("fetchExternalIPs() error = %v", err)
+	}
+
+	var got []string
+	for _, ip := range ips {
+		got = append(got, ip.String+"@"+ip.Node)
+	}
+	want := "192.0.2.1@node-f,192.0.2.9@node-g,198.51.100.7@node-b,203.0.113.20@node-a,2001:db8::1@node-g,2001:db8::2@node-a"
+	if strings.Join(got, ",") != want {
+		t.Errorf("fetchExternalIPs() = %v, want %s", got, want)
+	}
+}
+
+func TestFetchExternalIPsPaginated(t *testing.T) {
+	var nodes []corev1.Node
+	for i := 0; i < NodeListPageSize+2; i++ {
+		ip := net.IPv4(10, 0, byte(i/250), byte(i%250+1)).String()
+		nodes = append(nodes, *newTestNode(fmt.Sprintf("node-%04d", i), map[string]string{ExternalIPAnnotation: ip + ",203.0.113.1"}, nil))
+	}
+
+	// Serve the nodes in pages, like the API server does for limit and
+	// continue
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		start, _ := strconv.Atoi(r.URL.Query().Get("continue"))
+		end := min(start+limit, len(nodes))
+		list := corev1.NodeList{
+			TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"},
+			Items:    nodes[start:end],
+		}
+		if end < len(nodes) {
+			list.Continue = strconv.Itoa(end)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer server.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips, err := fetchExternalIPs(context.Background(), clientset, newFetchTestConfig())
+	if err != nil {
+		t.Fatalf("fetchExternalIPs() error = %v", err)
+	}
+
+	wantRequests := []string{fmt.Sprintf("limit=%d", NodeListPageSize), fmt.Sprintf("continue=%d&limit=%d", NodeListPageSize, NodeListPageSize)}
+	if strings.Join(requests, " ") != strings.Join(wantRequests, " ") {
+		t.Errorf("list requests = %v, want %v", requests, wantRequests)
+	}
+	if len(ips) != len(nodes)+1 {
+		t.Fatalf("fetchExternalIPs() returned %d IPs, want %d", len(ips), len(nodes)+1)
+	}
+	if got := ips[len(ips)-1].String + "@" + ips[len(ips)-1].Node; got != "203.0.113.1@node-0000" {
+		t.Errorf("shared IP = %s, want it attributed to the first node", got)
+	}
+}
+
+func TestFetchExternalIPsSelectorAndOptOut(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "203.0.113.1"}, map[string]string{"dns-sync": "enabled"}),
+		newTestNode("node-b", map[string]string{ExternalIPAnnotation: "203.0.113.2"}, nil),
+		newTestNode("node-c", map[string]string{ExternalIPAnnotation: "203.0.113.3", ExcludeAnnotation: "true"}, map[string]string{"dns-sync": "enabled"}),
+	)
+
+	config := newFetchTestConfig()
+	config.NodeSelector = "dns-sync=enabled"
+	ips, err := fetchExternalIPs(context.Background(), clientset, config)
+	if err != nil {
+		t.Fatalf("fetchExternalIPs() error = %v", err)
+	}
+	if got := strings.Join(ipStrings(ips), ","); got != "203.0.113.1" {
+		t.Errorf("fetchExternalIPs() = %s, want only the selected node that did not opt out", got)
+	}
+}
+
+func TestFetchExternalIPsExcludeTaints(t *testing.T) {
+	controlPlane := newTestNode("server", map[string]string{ExternalIPAnnotation: "203.0.113.1"}, nil)
+	controlPlane.Spec.Taints = []corev1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}}
+	preferNoSchedule := newTestNode("agent-a", map[string]string{ExternalIPAnnotation: "203.0.113.2"}, nil)
+	preferNoSchedule.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectPreferNoSchedule}}
+	clientset := fake.NewSimpleClientset(
+		controlPlane,
+		preferNoSchedule,
+		newTestNode("agent-b", map[string]string{ExternalIPAnnotation: "203.0.113.3"}, nil),
+	)
+
+	config := newFetchTestConfig()
+	config.ExcludeTaints = []string{"node-role.kubernetes.io/control-plane"}
+	ips, err := fetchExternalIPs(context.Background(), clientset, config)
+	if err != nil {
+		t.Fatalf("fetchExternalIPs() error = %v", err)
+	}
+	if got := strings.Join(ipStrings(ips), ","); got != "203.0.113.2,203.0.113.3" {
+		t.Errorf("fetchExternalIPs() = %s, want the agents only", got)
+	}
+}
+
+func TestFetchExternalIPsNoNodes(t *testing.T) {
+	ips, err := fetchExternalIPs(context.Background(), fake.NewSimpleClientset(), newFetchTestConfig())
+	if err != nil || len(ips) != 0 {
+		t.Errorf("fetchExternalIPs() without nodes = %v, error = %v, want none", ips, err)
+	}
+}
+
+func TestSyncDNSRecordsNodeRemoved(t *testing.T) {
+	t.Cleanup(func() { lastPublishedIPs = nil })
+
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "203.0.113.1,2001:db8::1"}, nil),
+		newTestNode("node-b", map[string]string{ExternalIPAnnotation: "203.0.113.2"}, nil),
+	)
+	mock := newMockPowerDNS(t, "example.com.")
+	providers := []DNSProvider{mock.target(t)}
+	config := newUpdateTestConfig()
+
+	ipProvider := newIPProvider(clientset, config)
+
+	if err := syncDNSRecords(context.Background(), ipProvider, providers, config); err != nil {
+		t.Fatalf("first syncDNSRecords() error = %v", err)
+	}
+	if got := strings.Join(mock.values("www.example.com.", RecordTypeA), ","); got != "203.0.113.1,203.0.113.2" {
+		t.Fatalf("A record after first sync = %s, want both nodes", got)
+	}
+
+	if err := clientset.CoreV1().Nodes().Delete(context.Background(), "node-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncDNSRecords(context.Background(), ipProvider, providers, config); err != nil {
+		t.Fatalf("second syncDNSRecords() error = %v", err)
+	}
+
+	if got := strings.Join(mock.values("www.example.com.", RecordTypeA), ","); got != "203.0.113.2" {
+		t.Errorf("A record after node removal = %s, want only node-b", got)
+	}
+	if got := mock.values("www.example.com.", RecordTypeAAAA); len(got) != 0 {
+		t.Errorf("AAAA record after node removal = %v, want it deleted", got)
+	}
+	if got := strings.Join(lastPublishedIPs, ","); got != "203.0.113.2" {
+		t.Errorf("lastPublishedIPs = %s, want only node-b", got)
+	}
+}
+
+func TestAppendUniqueIPsCanonicalIPv6(t *testing.T) {
+	annotation, _ := parseIPAddresses("2603:c022:5:1e00:0:0:0:1,203.0.113.5")
+	status, _ := parseIPAddresses("2603:c022:5:1e00::1,2603:C022:0005:1E00:0000:0000:0000:0001,203.0.113.5,2603:c022:5:1e00::2")
+
+	seen := make(map[string]string)
+	candidates := appendUniqueIPs(nil, seen, annotation, "node1", 0)
+	candidates = appendUniqueIPs(candidates, seen, status, "node2", 0)
+
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.String+"@"+c.Node)
+	}
+	want := "2603:c022:5:1e00:0:0:0:1@node1,203.0.113.5@node1,2603:c022:5:1e00::2@node2"
+	if strings.Join(got, ",") != want {
+		t.Errorf("appendUniqueIPs() = %v, want %s", got, want)
+	}
+}
+
+func TestAppendUniqueIPsCountsDuplicates(t *testing.T) {
+	oldMetrics := metrics
+	metrics = newMetrics()
+	defer func() { metrics = oldMetrics }()
+
+	ips, _ := parseIPAddresses("203.0.113.5")
+	seen := make(map[string]string)
+	candidates := appendUniqueIPs(nil, seen, ips, "node1", 0)
+	candidates = appendUniqueIPs(candidates, seen, ips, "node1", 0)
+	if metrics.duplicateIPs != 0 {
+		t.Errorf("duplicateIPs = %d after a repeat from the same node, want 0", metrics.duplicateIPs)
+	}
+
+	candidates = appendUniqueIPs(candidates, seen, ips, "node2", 0)
+	if len(candidates) != 1 || candidates[0].Node != "node1" {
+		t.Errorf("appendUniqueIPs() = %v, want the IP once, from node1", candidates)
+	}
+	if metrics.duplicateIPs != 1 {
+		t.Errorf("duplicateIPs = %d, want 1", metrics.duplicateIPs)
+	}
+}
+
+func TestLoadConfigIPFamily(t *testing.T) {
+	ips, _ := parseIPAddresses("203.0.113.5,2001:db8::1")
+
+	tests := []struct {
+		value      string
+		wantIPs    string
+		manageA    bool
+		manageAAAA bool
+		wantErr    bool
+	}{
+		{value: "", wantIPs: "203.0.113.5,2001:db8::1", manageA: true, manageAAAA: true},
+		{value: "all", wantIPs: "203.0.113.5,2001:db8::1", manageA: true, manageAAAA: true},
+		{value: "IPv4", wantIPs: "203.0.113.5", manageA: true, manageAAAA: false},
+		{value: "ipv6", wantIPs: "2001:db8::1", manageA: false, manageAAAA: true},
+		{value: "ipv5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("IP_FAMILY", tt.value)
+
+			config, err := loadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := strings.Join(ipStrings(filterIPAddresses(ips, config)), ","); got != tt.wantIPs {
+				t.Errorf("filterIPAddresses() = %s, want %s", got, tt.wantIPs)
+			}
+			if config.ManageA != tt.manageA || config.ManageAAAA != tt.manageAAAA {
+				t.Errorf("ManageA = %v, ManageAAAA = %v, want %v and %v", config.ManageA, config.ManageAAAA, tt.manageA, tt.manageAAAA)
+			}
+		})
+	}
+
+	t.Run("conflicts with MANAGE_A", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("IP_FAMILY", "ipv4")
+		t.Setenv("MANAGE_A", "false")
+		if _, err := loadConfig(); err == nil {
+			t.Error("loadConfig() should fail when IP_FAMILY=ipv4 and MANAGE_A=false")
+		}
+	})
+}
+
+func TestLoadConfigK8sMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "incluster", want: K8sModeInCluster},
+		{value: "Kubeconfig", want: K8sModeKubeconfig},
+		{value: "auto", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		setRequiredEnv(t)
+		t.Setenv("K8S_MODE", tt.value)
+
+		config, err := loadConfig()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("loadConfig() with K8S_MODE=%q error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if err == nil && config.K8sMode != tt.want {
+			t.Errorf("K8sMode = %q, want %q", config.K8sMode, tt.want)
+		}
+	}
+}
+
+func TestGetKubernetesClientInClusterModeFailsFast(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := getKubernetesClient("", K8sModeInCluster); err == nil {
+		t.Error("getKubernetesClient() in incluster mode should fail outside a cluster")
+	}
+}
+
+func TestParseAddressTypes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "ExternalIP", want: "ExternalIP"},
+		{value: "externalip, InternalIP", want: "ExternalIP,InternalIP"},
+		{value: "", want: ""},
+		{value: "Hostname", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAddressTypes(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseAddressTypes(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("parseAddressTypes(%q) = %v, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestOrderRecordValues(t *testing.T) {
+	values := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"}
+
+	config := &Config{}
+	if got := orderRecordValues(config, values); strings.Join(got, ",") != strings.Join(values, ",") {
+		t.Errorf("orderRecordValues() without SHUFFLE_RECORDS = %v, want %v", got, values)
+	}
+	if shuffleRecordType(config, RecordTypeA) {
+		t.Error("shuffleRecordType() should be false without SHUFFLE_RECORDS")
+	}
+
+	config.ShuffleRecords = true
+	if !shuffleRecordType(config, RecordTypeAAAA) || shuffleRecordType(config, RecordTypeTXT) {
+		t.Error("shuffleRecordType() should only be true for A and AAAA records")
+	}
+
+	orders := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		got := orderRecordValues(config, values)
+		if !recordValuesEqual(got, values) {
+			t.Fatalf("orderRecordValues() = %v, want a permutation of %v", got, values)
+		}
+		orders[strings.Join(got, ",")] = true
+	}
+	if len(orders) < 2 {
+		t.Error("orderRecordValues() with SHUFFLE_RECORDS never changed the order")
+	}
+	if values[0] != "203.0.113.1" {
+		t.Error("orderRecordValues() must not modify its input")
+	}
+}
+
+func TestLoadConfigAddressTypes(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("ADDRESS_TYPES", "InternalIP,ExternalIP")
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if strings.Join(config.AddressTypes, ",") != "InternalIP,ExternalIP" {
+		t.Errorf("AddressTypes = %v, want [InternalIP ExternalIP]", config.AddressTypes)
+	}
+
+	t.Setenv("ADDRESS_TYPES", "Hostname")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should fail on an invalid ADDRESS_TYPES entry")
+	}
+}
+
+func TestLoadConfigExtraIPs(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("EXTRA_IPS", "198.51.100.10, 2001:db8::10")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if got := strings.Join(ipStrings(config.ExtraIPs), ","); got != "198.51.100.10,2001:db8::10" {
+		t.Errorf("ExtraIPs = %s, want 198.51.100.10,2001:db8::10", got)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "300", want: 300},
+		{value: "300s", want: 300},
+		{value: "5m", want: 300},
+		{value: "1h30m", want: 5400},
+		{value: "0", want: 0},
+		{value: "five minutes", wantErr: true},
+		{value: "300x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTTL(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseTTL(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("parseTTL(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigTTLSeconds(t *testing.T) {
+	tests := []struct {
+		ttl  string
+		want int
+	}{
+		{"300", 300},
+		{"300s", 300},
+		{"5m", 300},
+		{"invalid", DefaultTTL},
+	}
+
+	for _, tt := range tests {
+		setRequiredEnv(t)
+		t.Setenv("DNS_TTL", tt.ttl)
+		t.Setenv("DNS_TTL_AAAA", tt.ttl)
+
+		config, err := loadConfig()
+		if err != nil {
+			t.Fatalf("loadConfig() with DNS_TTL=%q error = %v", tt.ttl, err)
+		}
+		if config.TTL != tt.want || recordTTL(config, RecordTypeAAAA) != tt.want {
+			t.Errorf("DNS_TTL=%q: TTL = %d, AAAA TTL = %d, want %d", tt.ttl, config.TTL, recordTTL(config, RecordTypeAAAA), tt.want)
+		}
+	}
+}
+
+func TestLoadConfigTTLBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     string
+		strict  string
+		want    int
+		wantErr bool
+	}{
+		{name: "in range", ttl: "5m", want: 300},
+		{name: "zero clamped", ttl: "0s", want: MinTTL},
+		{name: "huge clamped", ttl: "8760h", want: MaxTTL},
+		{name: "upper bound", ttl: "168h", strict: "true", want: MaxTTL},
+		{name: "zero strict", ttl: "0s", strict: "true", wantErr: true},
+		{name: "huge strict", ttl: "8760h", strict: "true", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("DNS_TTL", tt.ttl)
+			t.Setenv("STRICT_TTL", tt.strict)
+
+			config, err := loadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && config.TTL != tt.want {
+				t.Errorf("TTL = %d, want %d", config.TTL, tt.want)
+			}
+		})
+	}
+
+	t.Run("per-type override", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("DNS_TTL_A", "720h")
+		config, err := loadConfig()
+		if err != nil {
+			t.Fatalf("loadConfig() error = %v", err)
+		}
+		if config.TTLA != MaxTTL {
+			t.Errorf("TTLA = %d, want %d", config.TTLA, MaxTTL)
+		}
+	})
+}
+
+func TestParseIPListSeparator(t *testing.T) {
+	tests := []struct {
+		input     string
+		separator string
+		want      string
+	}{
+		{"1.2.3.4;5.6.7.8", ";", "1.2.3.4,5.6.7.8"},
+		{"1.2.3.4; 2001:db8::1;;", ";", "1.2.3.4,2001:db8::1"},
+		{"1.2.3.4;5.6.7.8,9.9.9.9", ";", "1.2.3.4,5.6.7.8,9.9.9.9"},
+		{"1.2.3.4;5.6.7.8", ",", ""},
+	}
+
+	for _, tt := range tests {
+		ips, err := parseIPList(tt.input, tt.separator)
+		if err != nil {
+			t.Fatalf("parseIPList(%q) error = %v", tt.input, err)
+		}
+		if got := strings.Join(ipStrings(ips), ","); got != tt.want {
+			t.Errorf("parseIPList(%q, %q) = %s, want %s", tt.input, tt.separator, got, tt.want)
+		}
+	}
+}
+
+func TestParseIPListJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"IPv4 and IPv6", `["1.2.3.4","2001:db8::1"]`, "1.2.3.4,2001:db8::1"},
+		{"With whitespace", ` [ "1.2.3.4" , " 5.6.7.8 ", "" ] `, "1.2.3.4,5.6.7.8"},
+		{"Invalid entry dropped", `["1.2.3.4","not-an-ip"]`, "1.2.3.4"},
+		{"Empty array", `[]`, ""},
+		{"Malformed JSON", `["1.2.3.4",`, "1.2.3.4"},
+		{"Not strings", `[1, 2]`, ""},
+		{"Unquoted entries", `[1.2.3.4,5.6.7.8]`, "1.2.3.4,5.6.7.8"},
+		{"Comma list still works", "1.2.3.4,2001:db8::1", "1.2.3.4,2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, err := parseIPAddresses(tt.input)
+			if err != nil {
+				t.Fatalf("parseIPAddresses(%q) error = %v", tt.input, err)
+			}
+			if got := strings.Join(ipStrings(ips), ","); got != tt.want {
+				t.Errorf("parseIPAddresses(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIPListQuotedAndBracketed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Quoted list", `"1.2.3.4, 2001:db8::1"`, "1.2.3.4,2001:db8::1"},
+		{"Single-quoted list", `'1.2.3.4,5.6.7.8'`, "1.2.3.4,5.6.7.8"},
+		{"Quoted entries", `"1.2.3.4", "2001:db8::1"`, "1.2.3.4,2001:db8::1"},
+		{"Bracketed list", `[1.2.3.4, 2001:db8::1]`, "1.2.3.4,2001:db8::1"},
+		{"Bracketed IPv6 entry", `1.2.3.4,[2001:db8::1]`, "1.2.3.4,2001:db8::1"},
+		{"Quoted and bracketed", `"[1.2.3.4, '5.6.7.8']"`, "1.2.3.4,5.6.7.8"},
+		{"Only quotes", `""`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, err := parseIPAddresses(tt.input)
+			if err != nil {
+				t.Fatalf("parseIPAddresses(%q) error = %v", tt.input, err)
+			}
+			if got := strings.Join(ipStrings(ips), ","); got != tt.want {
+				t.Errorf("parseIPAddresses(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSeparator(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ","},
+		{value: ";", want: ";"},
+		{value: "|", want: "|"},
+		{value: ":", wantErr: true},
+		{value: ";;", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		setRequiredEnv(t)
+		t.Setenv("SEPARATOR", tt.value)
+
+		config, err := loadConfig()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("loadConfig() with SEPARATOR=%q error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if err == nil && config.Separator != tt.want {
+			t.Errorf("Separator = %q, want %q", config.Separator, tt.want)
+		}
+	}
+}
+
+func TestAffixRecordName(t *testing.T) {
+	tests := []struct {
+		record string
+		prefix string
+		suffix string
+		want   string
+	}{
+		{"nodes.example.com", "prod", "", "prod.nodes.example.com."},
+		{"nodes.example.com.", ".prod.", "", "prod.nodes.example.com."},
+		{"nodes.example.com", "eu.prod", "", "eu.prod.nodes.example.com."},
+		{"nodes.example.com", "", "prod", "nodes.prod.example.com."},
+		{"nodes.example.com", "", ".prod", "nodes.prod.example.com."},
+		{"example.com", "", "prod", "prod.example.com."},
+		{"nodes.example.com", "a", "b", "a.nodes.b.example.com."},
+		{"nodes.other.org", "", "prod", "nodes.other.org."},
+		{"nodes.example.com", "", "", "nodes.example.com."},
+	}
+
+	for _, tt := range tests {
+		if got := affixRecordName(tt.record, "example.com.", tt.prefix, tt.suffix); got != tt.want {
+			t.Errorf("affixRecordName(%q, prefix %q, suffix %q) = %q, want %q", tt.record, tt.prefix, tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigRecordPrefix(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DNS_RECORD", "nodes.example.com, api.example.com")
+	t.Setenv("RECORD_PREFIX", "prod")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	want := "prod.nodes.example.com.,prod.api.example.com."
+	if got := strings.Join(config.DNSRecords, ","); got != want {
+		t.Errorf("DNSRecords = %s, want %s", got, want)
+	}
+}
+
+func TestUpdateDNSRecordsMaxIPsPerRecord(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	config := newUpdateTestConfig()
+	config.MaxIPsPerRecord = 2
+
+	ips, _ := parseIPAddresses("192.0.2.3,192.0.2.1,192.0.2.2,2001:db8::1")
+	sortIPAddresses(ips)
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, config, ips); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(mock.values("www.example.com.", RecordTypeA), ","); got != "192.0.2.1,192.0.2.2" {
+		t.Errorf("A values = %s, want the first 2 sorted IPs", got)
+	}
+	if got := strings.Join(mock.values("www.example.com.", RecordTypeAAAA), ","); got != "2001:db8::1" {
+		t.Errorf("AAAA values = %s, want 2001:db8::1", got)
+	}
+
+	_, _, dropped := familyRecordValues(config, ips)
+	if got := strings.Join(dropped, ","); got != "192.0.2.3" {
+		t.Errorf("dropped = %s, want 192.0.2.3", got)
+	}
+}
+
+func TestUpdateDNSRecordsPerFamilyNames(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeA, DefaultTTL, "192.0.2.9")
+	config := newUpdateTestConfig()
+	config.DNSRecordA = "v4.nodes.example.com."
+	config.DNSRecordAAAA = "v6.nodes.example.com."
+
+	ips, _ := parseIPAddresses("192.0.2.1,2001:db8::1")
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, config, ips); err != nil {
+		t.Fatalf("updateDNSRecords() error = %v", err)
+	}
+	want := []string{"REPLACE A v4.nodes.example.com. 192.0.2.1", "REPLACE AAAA v6.nodes.example.com. 2001:db8::1"}
+	if got := mock.takeChanges(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+	if got := strings.Join(mock.values("www.example.com.", RecordTypeA), ","); got != "192.0.2.9" {
+		t.Errorf("A record at DNS_RECORD = %s, want it left alone", got)
+	}
+
+	// Each family is cleaned up on its own
+	ips, _ = parseIPAddresses("192.0.2.1")
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, config, ips); err != nil {
+		t.Fatalf("updateDNSRecords() error = %v", err)
+	}
+	if got := mock.takeChanges(); strings.Join(got, ",") != "DELETE AAAA v6.nodes.example.com." {
+		t.Errorf("changes = %q, want only the AAAA record deleted", got)
+	}
+}
+
+func TestLoadConfigPerFamilyRecords(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DNS_RECORD_A", "v4.nodes.example.com")
+	t.Setenv("DNS_RECORD_AAAA", "v6.nodes.example.com.")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.DNSRecordA != "v4.nodes.example.com." || config.DNSRecordAAAA != "v6.nodes.example.com." {
+		t.Errorf("DNSRecordA = %s, DNSRecordAAAA = %s, want the v4 and v6 names", config.DNSRecordA, config.DNSRecordAAAA)
+	}
+
+	t.Setenv("DNS_RECORD_AAAA", "v6.nodes.example.org.")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should fail with DNS_RECORD_AAAA outside DNS_ZONE")
+	}
+}
+
+func TestNodeTTL(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 0},
+		{"30", 30},
+		{" 1m ", 60},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{"8760h", 0},
+	}
+	for _, tt := range tests {
+		node := newTestNode("node1", map[string]string{TTLAnnotation: tt.value}, nil)
+		if got := nodeTTL(node); got != tt.want {
+			t.Errorf("nodeTTL(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNodeTTLConfigOnlyLowers(t *testing.T) {
+	config := newUpdateTestConfig()
+	config.TTL = 300
+	config.TTLA = 60
+
+	tests := []struct {
+		name     string
+		ttl      int
+		wantA    int
+		wantAAAA int
+		wantTXT  int
+	}{
+		{name: "no annotation", ttl: 0, wantA: 60, wantAAAA: 300, wantTXT: 300},
+		{name: "higher than every TTL", ttl: 3600, wantA: 60, wantAAAA: 300, wantTXT: 300},
+		{name: "between the TTLs", ttl: 120, wantA: 60, wantAAAA: 120, wantTXT: 120},
+		{name: "lower than every TTL", ttl: 30, wantA: 30, wantAAAA: 30, wantTXT: 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, _ := parseIPAddresses("192.0.2.1")
+			ips[0].TTL = tt.ttl
+			got := nodeTTLConfig(config, ips)
+			if a, aaaa, txt := recordTTL(got, RecordTypeA), recordTTL(got, RecordTypeAAAA), recordTTL(got, RecordTypeTXT); a != tt.wantA || aaaa != tt.wantAAAA || txt != tt.wantTXT {
+				t.Errorf("TTLs A = %d, AAAA = %d, TXT = %d, want %d, %d and %d", a, aaaa, txt, tt.wantA, tt.wantAAAA, tt.wantTXT)
+			}
+		})
+	}
+	if config.TTL != 300 || config.TTLA != 60 || config.TTLAAAA != 0 {
+		t.Errorf("config TTLs = %d/%d/%d, want the configuration left untouched", config.TTL, config.TTLA, config.TTLAAAA)
+	}
+}
+
+func TestUpdateDNSRecordsNodeTTL(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "192.0.2.1", TTLAnnotation: "120"}, nil),
+		newTestNode("node-b", map[string]string{ExternalIPAnnotation: "192.0.2.2,2001:db8::2", TTLAnnotation: "30s"}, nil),
+		newTestNode("node-c", map[string]string{ExternalIPAnnotation: "192.0.2.3", TTLAnnotation: "invalid"}, nil),
+	)
+	ips, err := fetchExternalIPs(context.Background(), clientset, newFetchTestConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := newMockPowerDNS(t, "example.com.")
+	config := newUpdateTestConfig()
+	config.TTLA = 600
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, config, ips); err != nil {
+		t.Fatal(err)
+	}
+	for _, recordType := range []RecordType{RecordTypeA, RecordTypeAAAA} {
+		if _, ttl, _ := mock.target(t).GetRecords(context.Background(), "example.com.", "www.example.com.", recordType); ttl != 30 {
+			t.Errorf("%s TTL = %d, want the lowest node TTL 30", recordType, ttl)
+		}
+	}
+
+	// Without node-b, node-a's TTL applies
+	mock.takeChanges()
+	var rest []IPAddress
+	for _, ip := range ips {
+		if ip.Node != "node-b" {
+			rest = append(rest, ip)
+		}
+	}
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{mock.target(t)}, config, rest); err != nil {
+		t.Fatal(err)
+	}
+	if _, ttl, _ := mock.target(t).GetRecords(context.Background(), "example.com.", "www.example.com.", RecordTypeA); ttl != 120 {
+		t.Errorf("A TTL = %d, want 120", ttl)
+	}
+	if config.TTLA != 600 {
+		t.Errorf("TTLA = %d, want the configuration left untouched", config.TTLA)
+	}
+}
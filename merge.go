@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ManagedSetPrefix is prepended to a record name to name the TXT record that
+// lists the values this controller published there, used by MERGE_RECORDS.
+const ManagedSetPrefix = "_k8s-external-ip-managed."
+
+// With MERGE_RECORDS, an RRset may also hold values added by hand, such as a
+// static IP next to the node IPs. Those must survive syncs, while values this
+// controller published earlier must still go away when their node does. The
+// controller therefore remembers what it published in a TXT record at
+// ManagedSetPrefix+name, one quoted "TYPE=value,value" string per record
+// type, and on each sync keeps every existing value that it does not list:
+//
+//	published = current IPs ∪ (existing values − previously managed values)
+//
+// The TXT is widened to include the new IPs before the RRsets are written and
+// narrowed to exactly the new IPs afterwards, so a sync interrupted between
+// the writes never leaves a controller value that looks static.
+
+// managedSetName returns the name of the TXT record listing managed values.
+func managedSetName(recordName string) string {
+	return ManagedSetPrefix + recordName
+}
+
+// parseManagedSet decodes the values of a managed-set TXT record. Values
+// other than A and AAAA lists are ignored.
+func parseManagedSet(values []string) map[RecordType][]string {
+	set := make(map[RecordType][]string)
+	for _, value := range values {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			continue
+		}
+		name, list, ok := strings.Cut(unquoted, "=")
+		recordType := RecordType(name)
+		if !ok || (recordType != RecordTypeA && recordType != RecordTypeAAAA) {
+			continue
+		}
+		for _, v := range strings.Split(list, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				set[recordType] = append(set[recordType], v)
+			}
+		}
+	}
+	return set
+}
+
+// managedSetValues encodes a managed set as TXT values, sorted by type so
+// that unchanged sets are not rewritten.
+func managedSetValues(set map[RecordType][]string) []string {
+	var values []string
+	for recordType, list := range set {
+		if len(list) > 0 {
+			values = append(values, strconv.Quote(string(recordType)+"="+strings.Join(list, ",")))
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// readManagedSet returns the values previously published at recordName.
+func readManagedSet(ctx context.Context, provider DNSProvider, config *Config, zone, recordName string) (map[RecordType][]string, error) {
+	values, _, _, err := getRecordValues(ctx, provider, config, zone, managedSetName(recordName), RecordTypeTXT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read managed values of %s: %w", recordName, err)
+	}
+	return parseManagedSet(values), nil
+}
+
+// writeManagedSet stores the managed set of recordName, deleting the TXT
+// record once the controller manages no values there.
+func writeManagedSet(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone, recordName string, set map[RecordType][]string) error {
+	values := managedSetValues(set)
+	if len(values) == 0 {
+		deleteRecord(ctx, collector, provider, config, zone, managedSetName(recordName), RecordTypeTXT)
+		return nil
+	}
+	if err := changeRecord(ctx, collector, provider, config, zone, managedSetName(recordName), RecordTypeTXT, values); err != nil {
+		return fmt.Errorf("failed to write managed values of %s: %w", recordName, err)
+	}
+	return nil
+}
+
+// mergedRecordValues reads the RRset of the given type and returns current
+// followed by the existing values that were not published by the controller.
+func mergedRecordValues(ctx context.Context, provider DNSProvider, config *Config, zone, recordName string, recordType RecordType, managed, current []string) ([]string, error) {
+	existing, _, _, err := getRecordValues(ctx, provider, config, zone, recordName, recordType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s record for %s: %w", recordType, recordName, err)
+	}
+	return mergeRecordValues(existing, managed, current), nil
+}
+
+// mergeRecordValues returns current followed by the values of existing that
+// are neither in managed nor in current. IPs are compared in canonical form.
+func mergeRecordValues(existing, managed, current []string) []string {
+	skip := make(map[string]bool, len(managed)+len(current))
+	for _, v := range managed {
+		skip[canonicalValue(v)] = true
+	}
+	merged := append([]string(nil), current...)
+	for _, v := range current {
+		skip[canonicalValue(v)] = true
+	}
+
+	for _, v := range existing {
+		if key := canonicalValue(v); !skip[key] {
+			skip[key] = true
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// canonicalValue returns the canonical form of an IP record value, or the
+// value itself if it is not an IP.
+func canonicalValue(value string) string {
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return value
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeRecordValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		managed  []string
+		current  []string
+		want     []string
+	}{
+		{
+			name:     "keeps static values",
+			existing: []string{"203.0.113.5", "198.51.100.1"},
+			managed:  []string{"203.0.113.5"},
+			current:  []string{"203.0.113.5", "203.0.113.6"},
+			want:     []string{"203.0.113.5", "203.0.113.6", "198.51.100.1"},
+		},
+		{
+			name:     "drops values of removed nodes",
+			existing: []string{"203.0.113.5", "203.0.113.6", "198.51.100.1"},
+			managed:  []string{"203.0.113.5", "203.0.113.6"},
+			current:  []string{"203.0.113.5"},
+			want:     []string{"203.0.113.5", "198.51.100.1"},
+		},
+		{
+			name:     "compares IPv6 in canonical form",
+			existing: []string{"2001:db8::1", "2001:db8::53"},
+			managed:  []string{"2001:db8:0:0:0:0:0:1"},
+			current:  []string{"2001:DB8::2"},
+			want:     []string{"2001:DB8::2", "2001:db8::53"},
+		},
+		{
+			name:     "only static values left",
+			existing: []string{"203.0.113.5", "198.51.100.1"},
+			managed:  []string{"203.0.113.5"},
+			want:     []string{"198.51.100.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeRecordValues(tt.existing, tt.managed, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeRecordValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagedSetRoundTrip(t *testing.T) {
+	set := map[RecordType][]string{
+		RecordTypeAAAA: {"2001:db8::1"},
+		RecordTypeA:    {"203.0.113.5", "203.0.113.6"},
+		"MX":           nil,
+	}
+
+	values := managedSetValues(set)
+	want := []string{`"A=203.0.113.5,203.0.113.6"`, `"AAAA=2001:db8::1"`}
+	if !reflect.DeepEqual(values, want) {
+		t.Fatalf("managedSetValues() = %v, want %v", values, want)
+	}
+
+	parsed := parseManagedSet(append(values, `"v=spf1 -all"`, "unquoted"))
+	if len(parsed) != 2 || !reflect.DeepEqual(parsed[RecordTypeA], set[RecordTypeA]) || !reflect.DeepEqual(parsed[RecordTypeAAAA], set[RecordTypeAAAA]) {
+		t.Errorf("parseManagedSet() = %v, want the A and AAAA values", parsed)
+	}
+
+	if got := managedSetName("cluster.example.com."); got != "_k8s-external-ip-managed.cluster.example.com." {
+		t.Errorf("managedSetName() = %s", got)
+	}
+}
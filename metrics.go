@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMetricsAddr is the listen address of the metrics endpoint.
+const DefaultMetricsAddr = ":9090"
+
+// syncDurationBuckets are the upper bounds, in seconds, of the sync duration histogram.
+var syncDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics holds the process metrics exposed in the Prometheus text format.
+type Metrics struct {
+	mu sync.Mutex
+
+	syncTotal        uint64
+	syncErrorsTotal  uint64
+	powerDNSRequests map[powerDNSRequestKey]uint64
+	publishedIPs     int
+	duplicateIPs     uint64
+	zoneSerials      map[zoneSerialKey]uint32
+
+	syncDurationCounts []uint64 // Per bucket, non-cumulative
+	syncDurationSum    float64
+	syncDurationCount  uint64
+}
+
+type powerDNSRequestKey struct {
+	operation string
+	result    string
+}
+
+type zoneSerialKey struct {
+	target string
+	zone   string
+}
+
+var metrics = newMetrics()
+
+func newMetrics() *Metrics {
+	return &Metrics{
+		powerDNSRequests:   make(map[powerDNSRequestKey]uint64),
+		zoneSerials:        make(map[zoneSerialKey]uint32),
+		syncDurationCounts: make([]uint64, len(syncDurationBuckets)),
+	}
+}
+
+// observeSync records the outcome and duration of a sync run.
+func (m *Metrics) observeSync(duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.syncTotal++
+	if err != nil {
+		m.syncErrorsTotal++
+	}
+
+	seconds := duration.Seconds()
+	m.syncDurationSum += seconds
+	m.syncDurationCount++
+	for i, bound := range syncDurationBuckets {
+		if seconds <= bound {
+			m.syncDurationCounts[i]++
+			break
+		}
+	}
+}
+
+// observePowerDNSRequest counts a request sent to the PowerDNS API.
+func (m *Metrics) observePowerDNSRequest(operation string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.powerDNSRequests[powerDNSRequestKey{operation: operation, result: result}]++
+}
+
+// setPublishedIPs records how many external IPs are currently published.
+func (m *Metrics) setPublishedIPs(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publishedIPs = count
+}
+
+// setZoneSerial records the SOA serial of a zone last read after a change.
+func (m *Metrics) setZoneSerial(target, zone string, serial uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.zoneSerials[zoneSerialKey{target: target, zone: zone}] = serial
+}
+
+// observeDuplicateIP counts an IP that was reported by more than one node.
+func (m *Metrics) observeDuplicateIP() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.duplicateIPs++
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	fmt.Fprintln(w, "# HELP sync_total Total number of DNS sync runs.")
+	fmt.Fprintln(w, "# TYPE sync_total counter")
+	fmt.Fprintf(w, "sync_total %d\n", m.syncTotal)
+
+	fmt.Fprintln(w, "# HELP sync_errors_total Total number of failed DNS sync runs.")
+	fmt.Fprintln(w, "# TYPE sync_errors_total counter")
+	fmt.Fprintf(w, "sync_errors_total %d\n", m.syncErrorsTotal)
+
+	fmt.Fprintln(w, "# HELP powerdns_requests_total Total number of requests sent to the PowerDNS API.")
+	fmt.Fprintln(w, "# TYPE powerdns_requests_total counter")
+	keys := make([]powerDNSRequestKey, 0, len(m.powerDNSRequests))
+	for key := range m.powerDNSRequests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation < keys[j].operation
+		}
+		return keys[i].result < keys[j].result
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "powerdns_requests_total{operation=%q,result=%q} %d\n", key.operation, key.result, m.powerDNSRequests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP published_ips Number of external IP addresses currently published.")
+	fmt.Fprintln(w, "# TYPE published_ips gauge")
+	fmt.Fprintf(w, "published_ips %d\n", m.publishedIPs)
+
+	fmt.Fprintln(w, "# HELP powerdns_zone_serial SOA serial of the zone, read after the last sync that changed it.")
+	fmt.Fprintln(w, "# TYPE powerdns_zone_serial gauge")
+	serialKeys := make([]zoneSerialKey, 0, len(m.zoneSerials))
+	for key := range m.zoneSerials {
+		serialKeys = append(serialKeys, key)
+	}
+	sort.Slice(serialKeys, func(i, j int) bool {
+		if serialKeys[i].target != serialKeys[j].target {
+			return serialKeys[i].target < serialKeys[j].target
+		}
+		return serialKeys[i].zone < serialKeys[j].zone
+	})
+	for _, key := range serialKeys {
+		fmt.Fprintf(w, "powerdns_zone_serial{target=%q,zone=%q} %d\n", key.target, key.zone, m.zoneSerials[key])
+	}
+
+	fmt.Fprintln(w, "# HELP duplicate_ips_total Total number of IPs that were reported by more than one node.")
+	fmt.Fprintln(w, "# TYPE duplicate_ips_total counter")
+	fmt.Fprintf(w, "duplicate_ips_total %d\n", m.duplicateIPs)
+
+	fmt.Fprintln(w, "# HELP sync_duration_seconds Duration of DNS sync runs.")
+	fmt.Fprintln(w, "# TYPE sync_duration_seconds histogram")
+	var cumulative uint64
+	for i, bound := range syncDurationBuckets {
+		cumulative += m.syncDurationCounts[i]
+		fmt.Fprintf(w, "sync_duration_seconds_bucket{le=\"%g\"} %d\n", bound, cumulative)
+	}
+	fmt.Fprintf(w, "sync_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.syncDurationCount)
+	fmt.Fprintf(w, "sync_duration_seconds_sum %g\n", m.syncDurationSum)
+	fmt.Fprintf(w, "sync_duration_seconds_count %d\n", m.syncDurationCount)
+}
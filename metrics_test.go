@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := newMetrics()
+	m.observeSync(200*time.Millisecond, nil)
+	m.observeSync(3*time.Second, errors.New("boom"))
+	m.observePowerDNSRequest("change", nil)
+	m.observePowerDNSRequest("change", nil)
+	m.observePowerDNSRequest("delete", errors.New("boom"))
+	m.setPublishedIPs(3)
+	m.observeDuplicateIP()
+	m.setZoneSerial("http://pdns:8081", "example.com.", 2024010102)
+
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	expected := []string{
+		"sync_total 2",
+		"sync_errors_total 1",
+		`powerdns_requests_total{operation="change",result="success"} 2`,
+		`powerdns_requests_total{operation="delete",result="error"} 1`,
+		"published_ips 3",
+		"duplicate_ips_total 1",
+		`powerdns_zone_serial{target="http://pdns:8081",zone="example.com."} 2024010102`,
+		`sync_duration_seconds_bucket{le="0.1"} 0`,
+		`sync_duration_seconds_bucket{le="0.25"} 1`,
+		`sync_duration_seconds_bucket{le="5"} 2`,
+		`sync_duration_seconds_bucket{le="+Inf"} 2`,
+		"sync_duration_seconds_count 2",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics output missing %q:\n%s", line, body)
+		}
+	}
+}
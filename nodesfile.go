@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// listNodesFromFile calls fn for every node in the NODES_FILE node list
+// matching selector, in name order like the API server returns them. The
+// file holds a node list as returned by the Kubernetes API, e.g. the output
+// of "kubectl get nodes -o json", and is read again on every sync so that
+// edits take effect without a restart. This is a development aid for
+// running syncs without a cluster.
+func listNodesFromFile(path, selector string, fn func(node *corev1.Node)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read NODES_FILE: %w", err)
+	}
+	var nodes corev1.NodeList
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return fmt.Errorf("failed to parse NODES_FILE %s: %w", path, err)
+	}
+
+	matcher, err := labels.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid NODE_SELECTOR %q: %w", selector, err)
+	}
+
+	sort.Slice(nodes.Items, func(i, j int) bool {
+		return nodes.Items[i].Name < nodes.Items[j].Name
+	})
+	for i := range nodes.Items {
+		if matcher.Matches(labels.Set(nodes.Items[i].Labels)) {
+			fn(&nodes.Items[i])
+		}
+	}
+	return nil
+}
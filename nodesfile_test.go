@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeNodesFile writes a NODES_FILE with the given contents and returns its
+// path.
+func writeNodesFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFetchExternalIPsFromNodesFile(t *testing.T) {
+	config := newFetchTestConfig()
+	config.NodesFile = writeNodesFile(t, `{"items": [
+		{"metadata": {"name": "node-b", "labels": {"dns-sync": "enabled"}, "annotations": {"k3s.io/external-ip": "203.0.113.2,2001:db8::2"}}},
+		{"metadata": {"name": "node-a", "annotations": {"k3s.io/external-ip": "203.0.113.1"}}},
+		{"metadata": {"name": "node-c", "labels": {"dns-sync": "enabled"}, "annotations": {"k3s.io/external-ip": "203.0.113.3", "k3s.io/dns-exclude": "true"}}}
+	]}`)
+
+	// No clientset: the nodes are only read from the file
+	ips, err := fetchExternalIPs(context.Background(), nil, config)
+	if err != nil {
+		t.Fatalf("fetchExternalIPs() error = %v", err)
+	}
+	if got := strings.Join(ipStrings(ips), ","); got != "203.0.113.1,203.0.113.2,2001:db8::2" {
+		t.Errorf("fetchExternalIPs() = %s, want the IPs of node-a and node-b", got)
+	}
+
+	config.NodeSelector = "dns-sync=enabled"
+	if ips, err = fetchExternalIPs(context.Background(), nil, config); err != nil {
+		t.Fatalf("fetchExternalIPs() error = %v", err)
+	}
+	if got := strings.Join(ipStrings(ips), ","); got != "203.0.113.2,2001:db8::2" {
+		t.Errorf("fetchExternalIPs() = %s, want only the selected node that did not opt out", got)
+	}
+}
+
+func TestFetchExternalIPsNodesFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.json")},
+		{"invalid JSON", writeNodesFile(t, `{"items": [`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newFetchTestConfig()
+			config.NodesFile = tt.path
+			if _, err := fetchExternalIPs(context.Background(), nil, config); err == nil {
+				t.Error("fetchExternalIPs() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadConfigNodesFile(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("NODES_FILE", "./nodes.json")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.NodesFile != "./nodes.json" {
+		t.Errorf("NodesFile = %q, want ./nodes.json", config.NodesFile)
+	}
+
+	for env, value := range map[string]string{
+		"IP_SOURCE":       IPSourceService,
+		"WATCH_MODE":      "true",
+		"LEADER_ELECTION": "true",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			if _, err := loadConfig(); err == nil {
+				t.Errorf("loadConfig() with NODES_FILE and %s=%s succeeded, want an error", env, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+)
+
+// normalizeName returns a DNS name in the canonical form PowerDNS returns:
+// lowercase and with exactly one trailing dot, so that "Example.COM" and
+// "example.com." compare equal.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(name), ".")) + "."
+}
+
+// normalizeRecordValue returns a record value in the canonical form
+// PowerDNS returns for its type: compressed lowercase IPs for A and AAAA
+// records, and normalized names for records pointing at a name. Other
+// values, such as TXT strings, are case sensitive and kept as they are.
+func normalizeRecordValue(recordType RecordType, value string) string {
+	switch recordType {
+	case RecordTypeA, RecordTypeAAAA:
+		return canonicalValue(strings.TrimSpace(value))
+	case RecordTypeCNAME, RecordTypePTR, RecordTypeNS:
+		return normalizeName(value)
+	default:
+		return value
+	}
+}
+
+// normalizeRecordValues applies normalizeRecordValue to every value. It is
+// used both on the desired values and on those read back from PowerDNS, so
+// that notation differences are never mistaken for changes.
+func normalizeRecordValues(recordType RecordType, values []string) []string {
+	if values == nil {
+		return nil
+	}
+	normalized := make([]string, len(values))
+	for i, value := range values {
+		normalized[i] = normalizeRecordValue(recordType, value)
+	}
+	return normalized
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Example.COM.", "example.com."},
+		{"example.com", "example.com."},
+		{" WWW.example.com.. ", "www.example.com."},
+	}
+	for _, tt := range tests {
+		if normalizeName(tt.a) != normalizeName(tt.b) {
+			t.Errorf("normalizeName(%q) = %q, want it equal to normalizeName(%q) = %q", tt.a, normalizeName(tt.a), tt.b, normalizeName(tt.b))
+		}
+	}
+	if got := normalizeName("Example.COM."); got != "example.com." {
+		t.Errorf("normalizeName() = %q, want example.com.", got)
+	}
+}
+
+func TestNormalizeRecordValues(t *testing.T) {
+	tests := []struct {
+		recordType RecordType
+		values     []string
+		want       []string
+	}{
+		{RecordTypeA, []string{"192.0.2.1", " 192.0.2.2"}, []string{"192.0.2.1", "192.0.2.2"}},
+		{RecordTypeAAAA, []string{"2001:DB8:0:0:0:0:0:1"}, []string{"2001:db8::1"}},
+		{RecordTypeCNAME, []string{"WWW.Example.com"}, []string{"www.example.com."}},
+		{RecordTypePTR, []string{"Node1.Example.com."}, []string{"node1.example.com."}},
+		{RecordTypeTXT, []string{`"Owner=Prod"`}, []string{`"Owner=Prod"`}},
+	}
+	for _, tt := range tests {
+		if got := normalizeRecordValues(tt.recordType, tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeRecordValues(%s, %q) = %q, want %q", tt.recordType, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestChangeRecordIgnoresNotationDifferences(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeAAAA, DefaultTTL, "2001:db8::1")
+	mock.set("alias.example.com.", RecordTypeCNAME, DefaultTTL, "www.example.com.")
+	target := mock.target(t)
+	config := newUpdateTestConfig()
+
+	if err := changeRecord(context.Background(), newSyncCollector(), target, config, "example.com.", "www.example.com.", RecordTypeAAAA, []string{"2001:DB8:0:0:0:0:0:1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := changeRecord(context.Background(), newSyncCollector(), target, config, "example.com.", "alias.example.com.", RecordTypeCNAME, []string{"WWW.Example.COM"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := mock.takeChanges(); len(got) != 0 {
+		t.Errorf("changes = %q, want none for equivalent values", got)
+	}
+
+	// Values that do change are written in canonical form
+	if err := changeRecord(context.Background(), newSyncCollector(), target, config, "example.com.", "www.example.com.", RecordTypeAAAA, []string{"2001:DB8:0:0:0:0:0:2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := mock.takeChanges(); !reflect.DeepEqual(got, []string{"REPLACE AAAA www.example.com. 2001:db8::2"}) {
+		t.Errorf("changes = %q, want the canonical IPv6 written", got)
+	}
+}
+
+func TestGetRecordsMatchesCanonicalName(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	mock.set("www.example.com.", RecordTypeA, DefaultTTL, "192.0.2.1")
+
+	values, _, err := mock.target(t).GetRecords(context.Background(), "example.com.", "WWW.Example.COM.", RecordTypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []string{"192.0.2.1"}) {
+		t.Errorf("GetRecords() = %v, want the record despite the name's case", values)
+	}
+}
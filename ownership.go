@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// OwnershipHeritage identifies ownership TXT records written by this tool.
+const OwnershipHeritage = "k8s-external-ip-powerdns"
+
+// Ownership states of a record name, derived from its TXT records.
+const (
+	ownershipNone    = iota // no ownership TXT present
+	ownershipOurs           // TXT names this instance as owner
+	ownershipForeign        // TXT names another owner or controller
+)
+
+// ownershipValue returns the quoted TXT content claiming a record for ownerID.
+func ownershipValue(ownerID string) string {
+	return strconv.Quote(fmt.Sprintf("heritage=%s,owner=%s", OwnershipHeritage, ownerID))
+}
+
+// classifyOwnership inspects the TXT values at a record name. It returns the
+// ownership state and the TXT values unrelated to ownership, which must be
+// preserved when the ownership record is written.
+func classifyOwnership(values []string, ownerID string) (int, []string) {
+	state := ownershipNone
+	var others []string
+	for _, value := range values {
+		switch {
+		case value == ownershipValue(ownerID):
+			if state == ownershipNone {
+				state = ownershipOurs
+			}
+		case strings.Contains(value, "heritage="):
+			// Written by another instance or another controller such as external-dns
+			state = ownershipForeign
+		default:
+			others = append(others, value)
+		}
+	}
+	return state, others
+}
+
+// claimOwnership reads the ownership TXT of a record name and, unless it
+// belongs to someone else, makes sure it names this instance. It returns
+// the state found before claiming.
+func claimOwnership(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone, recordName string) (int, error) {
+	values, _, _, err := getRecordValues(ctx, provider, config, zone, recordName, RecordTypeTXT)
+	if err != nil {
+		return ownershipNone, fmt.Errorf("failed to read ownership TXT for %s: %w", recordName, err)
+	}
+
+	state, others := classifyOwnership(values, config.TXTOwnerID)
+	if state == ownershipForeign {
+		return state, nil
+	}
+
+	if state == ownershipNone {
+		slog.Info("Claiming ownership of record", "record", recordName, "owner", config.TXTOwnerID)
+	}
+	claimed := append(others, ownershipValue(config.TXTOwnerID))
+	if err := changeRecord(ctx, collector, provider, config, zone, recordName, RecordTypeTXT, claimed); err != nil {
+		return state, fmt.Errorf("failed to write ownership TXT for %s: %w", recordName, err)
+	}
+	return state, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyOwnership(t *testing.T) {
+	ours := ownershipValue("cluster-a")
+	theirs := ownershipValue("cluster-b")
+	externalDNS := `"heritage=external-dns,external-dns/owner=default"`
+	spf := `"v=spf1 -all"`
+
+	tests := []struct {
+		name       string
+		values     []string
+		wantState  int
+		wantOthers []string
+	}{
+		{name: "no TXT", values: nil, wantState: ownershipNone},
+		{name: "unrelated TXT only", values: []string{spf}, wantState: ownershipNone, wantOthers: []string{spf}},
+		{name: "ours", values: []string{spf, ours}, wantState: ownershipOurs, wantOthers: []string{spf}},
+		{name: "other instance", values: []string{theirs}, wantState: ownershipForeign},
+		{name: "external-dns", values: []string{externalDNS}, wantState: ownershipForeign},
+		{name: "ours and foreign", values: []string{ours, externalDNS}, wantState: ownershipForeign},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, others := classifyOwnership(tt.values, "cluster-a")
+			if state != tt.wantState {
+				t.Errorf("state = %d, want %d", state, tt.wantState)
+			}
+			if !reflect.DeepEqual(others, tt.wantOthers) {
+				t.Errorf("others = %v, want %v", others, tt.wantOthers)
+			}
+		})
+	}
+}
+
+func TestLoadConfigTXTOwnerID(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("TXT_OWNER_ID", "bad,owner")
+
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should reject TXT_OWNER_ID containing a comma")
+	}
+}
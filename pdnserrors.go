@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+// Classes of PowerDNS API failures, as reported by powerDNSErrorKind.
+const (
+	errorKindNotFound  = "not_found"
+	errorKindAuth      = "auth"
+	errorKindClient    = "client"
+	errorKindServer    = "server"
+	errorKindTransport = "transport"
+)
+
+// powerDNSStatus returns the HTTP status code of a PowerDNS API error, or
+// false if err did not come from an API response.
+func powerDNSStatus(err error) (int, bool) {
+	var apiErr *powerdns.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode, true
+	}
+	return 0, false
+}
+
+// isNotFound reports whether err is a PowerDNS 404 response.
+func isNotFound(err error) bool {
+	status, ok := powerDNSStatus(err)
+	return ok && status == http.StatusNotFound
+}
+
+// isAuthError reports whether err is a PowerDNS 401 or 403 response, which
+// usually means a wrong or under-privileged API key.
+func isAuthError(err error) bool {
+	status, ok := powerDNSStatus(err)
+	return ok && (status == http.StatusUnauthorized || status == http.StatusForbidden)
+}
+
+// isServerError reports whether err is a PowerDNS 5xx response.
+func isServerError(err error) bool {
+	status, ok := powerDNSStatus(err)
+	return ok && status >= 500
+}
+
+// powerDNSErrorKind classifies err for logging.
+func powerDNSErrorKind(err error) string {
+	switch {
+	case isNotFound(err):
+		return errorKindNotFound
+	case isAuthError(err):
+		return errorKindAuth
+	case isServerError(err):
+		return errorKindServer
+	}
+	if _, ok := powerDNSStatus(err); ok {
+		return errorKindClient
+	}
+	return errorKindTransport
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+func TestPowerDNSErrorKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		kind     string
+		notFound bool
+	}{
+		{name: "404", err: &powerdns.Error{StatusCode: 404, Message: "Not Found"}, kind: errorKindNotFound, notFound: true},
+		{name: "wrapped 404", err: fmt.Errorf("deleting: %w", &powerdns.Error{StatusCode: 404}), kind: errorKindNotFound, notFound: true},
+		{name: "401", err: &powerdns.Error{StatusCode: 401}, kind: errorKindAuth},
+		{name: "403", err: &powerdns.Error{StatusCode: 403}, kind: errorKindAuth},
+		{name: "422", err: &powerdns.Error{StatusCode: 422, Message: "RRset not found"}, kind: errorKindClient},
+		{name: "500", err: &powerdns.Error{StatusCode: 500}, kind: errorKindServer},
+		{name: "transport", err: errors.New("dial tcp: 404 not found"), kind: errorKindTransport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := powerDNSErrorKind(tt.err); got != tt.kind {
+				t.Errorf("powerDNSErrorKind() = %s, want %s", got, tt.kind)
+			}
+			if got := isNotFound(tt.err); got != tt.notFound {
+				t.Errorf("isNotFound() = %v, want %v", got, tt.notFound)
+			}
+		})
+	}
+}
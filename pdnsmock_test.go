@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+// mockPowerDNS is an in-memory stand-in for the subset of the PowerDNS API
+// the controller uses: listing servers, reading a zone and its RRsets, and
+// replacing or deleting RRsets. It serves a single zone of server id
+// localhost, records every RRset change it receives and bumps the zone serial
+// on each PATCH.
+type mockPowerDNS struct {
+	*httptest.Server
+	zone string
+
+	mu      sync.Mutex
+	rrsets  map[string]mockRRset
+	changes []string
+	serial  uint32
+	absent  bool // The zone does not exist until it is created
+}
+
+// mockRRset is the stored state of one RRset.
+type mockRRset struct {
+	name       string
+	recordType RecordType
+	ttl        uint32
+	values     []string
+}
+
+// newMockPowerDNS starts a mock server for zone, closed when the test ends.
+func newMockPowerDNS(t *testing.T, zone string) *mockPowerDNS {
+	t.Helper()
+	m := &mockPowerDNS{zone: zone, rrsets: make(map[string]mockRRset)}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	t.Cleanup(m.Close)
+	return m
+}
+
+// target returns a PowerDNS target pointing at the mock server.
+func (m *mockPowerDNS) target(t *testing.T) *PowerDNSTarget {
+	t.Helper()
+	targets, err := newPowerDNSTargets(&Config{PowerDNSURLs: []string{m.URL}, PowerDNSServerID: "localhost", PowerDNSAPIKey: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return targets[0]
+}
+
+// set stores an RRset as if it had been created out of band.
+func (m *mockPowerDNS) set(name string, recordType RecordType, ttl uint32, values ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rrsets[mockRRsetKey(name, recordType)] = mockRRset{name: name, recordType: recordType, ttl: ttl, values: values}
+}
+
+// values returns the stored values of an RRset, or nil if it does not exist.
+func (m *mockPowerDNS) values(name string, recordType RecordType) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rrsets[mockRRsetKey(name, recordType)].values
+}
+
+// takeChanges returns the changes received since the last call, each as
+// "REPLACE <type> <name> <value,value>", "DELETE <type> <name>" or
+// "CREATE <kind> <zone> <nameserver,nameserver>".
+func (m *mockPowerDNS) takeChanges() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changes := m.changes
+	m.changes = nil
+	return changes
+}
+
+func mockRRsetKey(name string, recordType RecordType) string {
+	return strings.ToLower(name) + " " + string(recordType)
+}
+
+func (m *mockPowerDNS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/api/v1/servers" && r.Method == http.MethodGet {
+		json.NewEncoder(w).Encode([]powerdns.Server{{ID: powerdns.String("localhost")}})
+		return
+	}
+
+	if r.URL.Path == "/api/v1/servers/localhost/zones" && r.Method == http.MethodPost {
+		var zone powerdns.Zone
+		if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintf(w, `{"error": %q}`, err.Error())
+			return
+		}
+		m.mu.Lock()
+		m.absent = false
+		m.changes = append(m.changes, fmt.Sprintf("CREATE %s %s %s", *zone.Kind, *zone.Name, strings.Join(zone.Nameservers, ",")))
+		m.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(zone)
+		return
+	}
+
+	m.mu.Lock()
+	absent := m.absent
+	m.mu.Unlock()
+	if r.URL.Path != "/api/v1/servers/localhost/zones/"+m.zone || absent {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Not Found"}`))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(m.zoneContents(r.URL.Query().Get("rrset_name"), RecordType(r.URL.Query().Get("rrset_type"))))
+	case http.MethodPatch:
+		var rrsets powerdns.RRsets
+		if err := json.NewDecoder(r.Body).Decode(&rrsets); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintf(w, `{"error": %q}`, err.Error())
+			return
+		}
+		m.apply(rrsets.Sets)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// zoneContents returns the zone with the RRsets matching the optional name
+// and type filters, sorted for stable output.
+func (m *mockPowerDNS) zoneContents(name string, recordType RecordType) powerdns.Zone {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zone := powerdns.Zone{Name: powerdns.String(m.zone), Serial: powerdns.Uint32(m.serial)}
+	for _, rrset := range m.rrsets {
+		if (name != "" && !strings.EqualFold(rrset.name, name)) || (recordType != "" && rrset.recordType != recordType) {
+			continue
+		}
+		result := powerdns.RRset{
+			Name: powerdns.String(rrset.name),
+			Type: powerdns.RRTypePtr(powerdns.RRType(rrset.recordType)),
+			TTL:  powerdns.Uint32(rrset.ttl),
+		}
+		for _, value := range rrset.values {
+			result.Records = append(result.Records, powerdns.Record{Content: powerdns.String(value), Disabled: powerdns.Bool(false)})
+		}
+		zone.RRsets = append(zone.RRsets, result)
+	}
+	sort.Slice(zone.RRsets, func(i, j int) bool {
+		return mockRRsetKey(*zone.RRsets[i].Name, RecordType(*zone.RRsets[i].Type)) < mockRRsetKey(*zone.RRsets[j].Name, RecordType(*zone.RRsets[j].Type))
+	})
+	return zone
+}
+
+// apply performs and records the changes of a PATCH request.
+func (m *mockPowerDNS) apply(rrsets []powerdns.RRset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.serial++
+	for _, rrset := range rrsets {
+		name, recordType := *rrset.Name, RecordType(*rrset.Type)
+		key := mockRRsetKey(name, recordType)
+
+		if *rrset.ChangeType == powerdns.ChangeTypeDelete {
+			delete(m.rrsets, key)
+			m.changes = append(m.changes, fmt.Sprintf("DELETE %s %s", recordType, name))
+			continue
+		}
+
+		var values []string
+		for _, record := range rrset.Records {
+			values = append(values, *record.Content)
+		}
+		m.rrsets[key] = mockRRset{name: name, recordType: recordType, ttl: *rrset.TTL, values: values}
+		m.changes = append(m.changes, fmt.Sprintf("REPLACE %s %s %s", recordType, name, strings.Join(values, ",")))
+	}
+}
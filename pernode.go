@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PerNodeIndexPrefix is prepended to the first DNS record to name the TXT
+// record listing the per-node records published by PER_NODE_RECORDS.
+const PerNodeIndexPrefix = "_k8s-external-ip-nodes."
+
+// nodeRecordPlaceholder matches the placeholders of a PER_NODE_RECORDS
+// template: {node}, {label:<key>} and {annotation:<key>}.
+var nodeRecordPlaceholder = regexp.MustCompile(`\{(node|label:[^{}]+|annotation:[^{}]+)\}`)
+
+// With PER_NODE_RECORDS, every node's IPs are also published under a name of
+// its own, such as node1.nodes.example.com. Nodes come and go, so the names
+// published are remembered in a TXT record at PerNodeIndexPrefix plus the
+// first DNS record, one quoted name per value. Names listed there that no
+// node maps to anymore have their records deleted. Like the MERGE_RECORDS
+// managed set, the index is widened before the records are written and
+// narrowed afterwards, so an interrupted sync never forgets a record.
+
+// validateNodeRecordTemplate checks that a PER_NODE_RECORDS template refers
+// to the node, so that nodes don't all map to the same name.
+func validateNodeRecordTemplate(template string) error {
+	if !nodeRecordPlaceholder.MatchString(template) {
+		return fmt.Errorf("%q contains none of {node}, {label:<key>} or {annotation:<key>}", template)
+	}
+	if rest := nodeRecordPlaceholder.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("%q contains an unknown placeholder", template)
+	}
+	return nil
+}
+
+// nodeRecordName expands a PER_NODE_RECORDS template for the node. It fails
+// if a referenced label or annotation is missing or empty.
+func nodeRecordName(template string, node *corev1.Node) (string, error) {
+	var err error
+	name := nodeRecordPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		field := placeholder[1 : len(placeholder)-1]
+		var value string
+		switch {
+		case field == "node":
+			value = node.Name
+		case strings.HasPrefix(field, "label:"):
+			value = node.Labels[strings.TrimPrefix(field, "label:")]
+		default:
+			value = node.Annotations[strings.TrimPrefix(field, "annotation:")]
+		}
+		if value == "" && err == nil {
+			err = fmt.Errorf("node has no value for %s", placeholder)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return validateDNSRecord(strings.ToLower(name)), nil
+}
+
+// perNodeIndexName returns the name of the TXT record listing the per-node records.
+func perNodeIndexName(config *Config) string {
+	return PerNodeIndexPrefix + validateDNSRecord(config.DNSRecords[0])
+}
+
+// updatePerNodeRecords publishes the IPs of every node under its per-node
+// name and deletes the per-node records of nodes that are gone.
+func updatePerNodeRecords(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone string, ipAddresses []IPAddress) error {
+	ipv4Records := make(map[string][]string)
+	ipv6Records := make(map[string][]string)
+	for _, ip := range ipAddresses {
+		if ip.NodeRecord == "" {
+			continue
+		}
+		if !recordInZone(ip.NodeRecord, zone) {
+			slog.Warn("Per-node record is not in the DNS zone, skipping", "record", ip.NodeRecord, "zone", zone, "node", ip.Node)
+			continue
+		}
+		if ip.IsIPv6 {
+			ipv6Records[ip.NodeRecord] = append(ipv6Records[ip.NodeRecord], ip.String)
+		} else {
+			ipv4Records[ip.NodeRecord] = append(ipv4Records[ip.NodeRecord], ip.String)
+		}
+	}
+
+	desired := make(map[string]bool)
+	for name := range ipv4Records {
+		desired[name] = true
+	}
+	for name := range ipv6Records {
+		desired[name] = true
+	}
+
+	indexValues, _, _, err := getRecordValues(ctx, provider, config, zone, perNodeIndexName(config), RecordTypeTXT)
+	if err != nil {
+		return fmt.Errorf("failed to read per-node record index: %w", err)
+	}
+	previous := parsePerNodeIndex(indexValues)
+
+	widened := make(map[string]bool, len(desired)+len(previous))
+	for name := range desired {
+		widened[name] = true
+	}
+	for _, name := range previous {
+		widened[name] = true
+	}
+	if err := writePerNodeIndex(ctx, collector, provider, config, zone, widened); err != nil {
+		return err
+	}
+
+	for _, name := range sortedNames(widened) {
+		if !desired[name] {
+			slog.Info("Node of per-node record is gone, removing its records", "record", name)
+		}
+		if err := updateDNSRecord(ctx, collector, provider, config, zone, name, ipv4Records[name], ipv6Records[name]); err != nil {
+			return fmt.Errorf("failed to update per-node record %s: %w", name, err)
+		}
+	}
+
+	return writePerNodeIndex(ctx, collector, provider, config, zone, desired)
+}
+
+// parsePerNodeIndex decodes the values of the per-node record index.
+func parsePerNodeIndex(values []string) []string {
+	var names []string
+	for _, value := range values {
+		if name, err := strconv.Unquote(value); err == nil && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// writePerNodeIndex stores the per-node record names, deleting the index
+// once there are none.
+func writePerNodeIndex(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone string, names map[string]bool) error {
+	var values []string
+	for _, name := range sortedNames(names) {
+		values = append(values, strconv.Quote(name))
+	}
+	if len(values) == 0 {
+		deleteRecord(ctx, collector, provider, config, zone, perNodeIndexName(config), RecordTypeTXT)
+		return nil
+	}
+	if err := changeRecord(ctx, collector, provider, config, zone, perNodeIndexName(config), RecordTypeTXT, values); err != nil {
+		return fmt.Errorf("failed to write per-node record index: %w", err)
+	}
+	return nil
+}
+
+// sortedNames returns the keys of names in order.
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateNodeRecordTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{"{node}.nodes.example.com.", false},
+		{"{label:topology.kubernetes.io/zone}-{node}.example.com", false},
+		{"{annotation:example.com/dns-name}", false},
+		{"nodes.example.com.", true},
+		{"{hostname}.example.com.", true},
+		{"{node}.{region}.example.com.", true},
+	}
+
+	for _, tt := range tests {
+		if err := validateNodeRecordTemplate(tt.template); (err != nil) != tt.wantErr {
+			t.Errorf("validateNodeRecordTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNodeRecordName(t *testing.T) {
+	node := newTestNode("Node-1", map[string]string{"example.com/dns-name": "edge1"}, map[string]string{"region": "eu"})
+
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{template: "{node}.nodes.example.com", want: "node-1.nodes.example.com."},
+		{template: "{node}.{label:region}.example.com.", want: "node-1.eu.example.com."},
+		{template: "{annotation:example.com/dns-name}.example.com", want: "edge1.example.com."},
+		{template: "{label:missing}.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := nodeRecordName(tt.template, node)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("nodeRecordName(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("nodeRecordName(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestPerNodeRecords(t *testing.T) {
+	t.Cleanup(func() { lastPublishedIPs = nil })
+
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "203.0.113.1,2001:db8::1"}, nil),
+		newTestNode("node-b", map[string]string{ExternalIPAnnotation: "203.0.113.2"}, nil),
+		newTestNode("node-c", map[string]string{ExternalIPAnnotation: "203.0.113.3"}, nil),
+	)
+	mock := newMockPowerDNS(t, "example.com.")
+	providers := []DNSProvider{mock.target(t)}
+	config := newUpdateTestConfig()
+	config.PerNodeRecords = "{node}.nodes.example.com."
+	index := PerNodeIndexPrefix + "www.example.com."
+	ipProvider := newIPProvider(clientset, config)
+
+	if err := syncDNSRecords(context.Background(), ipProvider, providers, config); err != nil {
+		t.Fatalf("first syncDNSRecords() error = %v", err)
+	}
+	for name, want := range map[string]string{
+		"www.example.com. A":             "203.0.113.1,203.0.113.2,203.0.113.3",
+		"node-a.nodes.example.com. A":    "203.0.113.1",
+		"node-a.nodes.example.com. AAAA": "2001:db8::1",
+		"node-b.nodes.example.com. A":    "203.0.113.2",
+		"node-c.nodes.example.com. A":    "203.0.113.3",
+	} {
+		recordName, recordType, _ := strings.Cut(name, " ")
+		if got := strings.Join(mock.values(recordName, RecordType(recordType)), ","); got != want {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+	wantIndex := `"node-a.nodes.example.com.","node-b.nodes.example.com.","node-c.nodes.example.com."`
+	if got := strings.Join(mock.values(index, RecordTypeTXT), ","); got != wantIndex {
+		t.Errorf("index = %s, want %s", got, wantIndex)
+	}
+
+	if err := clientset.CoreV1().Nodes().Delete(context.Background(), "node-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	mock.takeChanges()
+	if err := syncDNSRecords(context.Background(), ipProvider, providers, config); err != nil {
+		t.Fatalf("second syncDNSRecords() error = %v", err)
+	}
+
+	for _, recordType := range []RecordType{RecordTypeA, RecordTypeAAAA} {
+		if got := mock.values("node-a.nodes.example.com.", recordType); len(got) != 0 {
+			t.Errorf("%s record of the removed node = %v, want it deleted", recordType, got)
+		}
+	}
+	if got := strings.Join(mock.values("node-b.nodes.example.com.", RecordTypeA), ","); got != "203.0.113.2" {
+		t.Errorf("A record of node-b = %s, want it kept", got)
+	}
+	wantIndex = `"node-b.nodes.example.com.","node-c.nodes.example.com."`
+	if got := strings.Join(mock.values(index, RecordTypeTXT), ","); got != wantIndex {
+		t.Errorf("index after removal = %s, want %s", got, wantIndex)
+	}
+	for _, change := range mock.takeChanges() {
+		if !strings.Contains(change, index) && (strings.Contains(change, "node-b") || strings.Contains(change, "node-c")) {
+			t.Errorf("unchanged per-node record was rewritten: %s", change)
+		}
+	}
+}
+
+func TestLoadConfigPerNodeRecords(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("PER_NODE_RECORDS", "{node}.nodes.example.com.")
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.PerNodeRecords != "{node}.nodes.example.com." {
+		t.Errorf("PerNodeRecords = %q, want the template", config.PerNodeRecords)
+	}
+
+	t.Setenv("PER_NODE_RECORDS", "nodes.example.com.")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should reject a template without placeholders")
+	}
+}
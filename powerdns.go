@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joeig/go-powerdns/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// DefaultPowerDNSTimeout bounds each PowerDNS API request.
+const DefaultPowerDNSTimeout = 30 * time.Second
+
+// maxLoggedBodySize caps the request and response bodies logged at debug
+// level, as a zone listing can be megabytes.
+const maxLoggedBodySize = 64 << 10
+
+// PowerDNSTarget is one PowerDNS API server that receives record updates.
+// It is the DNSProvider for PowerDNS.
+type PowerDNSTarget struct {
+	URL    string
+	Client *powerdns.Client
+
+	// RECORD_COMMENT template set on written RRsets, if any
+	recordComment string
+
+	// For API calls go-powerdns does not provide, such as rectify
+	apiKey     string
+	httpClient *http.Client
+}
+
+// parsePowerDNSURLs splits a comma-separated POWERDNS_URL value, dropping
+// empty entries and duplicates.
+func parsePowerDNSURLs(value string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, url := range strings.Split(value, ",") {
+		url = strings.TrimSpace(url)
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// newPowerDNSTargets creates a client for every configured PowerDNS URL.
+// It fails if the configured TLS files can't be loaded.
+func newPowerDNSTargets(config *Config) ([]*PowerDNSTarget, error) {
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// All targets share one transport so connections are pooled per host
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxyFromEnvironment()
+	httpClient := &http.Client{
+		Timeout:   config.PowerDNSTimeout,
+		Transport: &userAgentTransport{base: &debugTransport{base: transport}, userAgent: config.UserAgent},
+	}
+
+	targets := make([]*PowerDNSTarget, 0, len(config.PowerDNSURLs))
+	for _, url := range config.PowerDNSURLs {
+		targets = append(targets, &PowerDNSTarget{
+			URL: url,
+			Client: powerdns.New(
+				url,
+				config.PowerDNSServerID,
+				powerdns.WithAPIKey(config.PowerDNSAPIKey),
+				powerdns.WithHTTPClient(httpClient),
+			),
+			recordComment: config.RecordComment,
+			apiKey:        config.PowerDNSAPIKey,
+			httpClient:    httpClient,
+		})
+	}
+	return targets, nil
+}
+
+// proxyFromEnvironment returns the proxy function for the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY variables (or their lowercase forms) as they are
+// set now. http.ProxyFromEnvironment reads them only once per process, which
+// hides changes from clients built later, such as on a configuration reload.
+func proxyFromEnvironment() func(*http.Request) (*url.URL, error) {
+	proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// userAgentTransport sets the User-Agent header of every request, replacing
+// the generic one of go-powerdns so that PowerDNS admins can tell this
+// client apart in their logs.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.userAgent == "" {
+		return t.base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// debugTransport logs every PowerDNS request and response, bodies included,
+// when debug logging is enabled, to diagnose rejected record formats. The
+// X-API-Key header is redacted.
+type debugTransport struct {
+	base http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return t.base.RoundTrip(req)
+	}
+
+	var requestBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		// Read a copy so that the body sent is left untouched
+		body, err := req.GetBody()
+		if err == nil {
+			requestBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+	slog.DebugContext(ctx, "PowerDNS API request", "method", req.Method, "url", req.URL.String(), "headers", redactedHeaders(req.Header), "body", loggedBody(requestBody))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		slog.DebugContext(ctx, "PowerDNS API request failed", "method", req.Method, "url", req.URL.String(), "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+	slog.DebugContext(ctx, "PowerDNS API response", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode, "duration", time.Since(start), "body", loggedBody(responseBody))
+	return resp, nil
+}
+
+// redactedHeaders returns the headers for logging, with the API key hidden.
+func redactedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if http.CanonicalHeaderKey(name) == "X-Api-Key" {
+			value = redactedValue
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// loggedBody returns body as text for logging, truncated to
+// maxLoggedBodySize.
+func loggedBody(body []byte) string {
+	if len(body) > maxLoggedBodySize {
+		return string(body[:maxLoggedBodySize]) + fmt.Sprintf("... (%d bytes truncated)", len(body)-maxLoggedBodySize)
+	}
+	return string(body)
+}
+
+// defaultUserAgent identifies this tool and its version, e.g.
+// "k8s-external-ip-powerdns/1.2.0".
+func defaultUserAgent() string {
+	return "k8s-external-ip-powerdns/" + version
+}
+
+// Name returns the URL of the PowerDNS server.
+func (t *PowerDNSTarget) Name() string {
+	return t.URL
+}
+
+// GetRecords reads an RRset from PowerDNS.
+func (t *PowerDNSTarget) GetRecords(ctx context.Context, zone, name string, recordType RecordType) ([]string, uint32, error) {
+	spanCtx, span := startSpan(ctx, "powerdns.Get", recordSpanAttributes(zone, name, recordType)...)
+	rrsets, err := t.Client.Records.Get(spanCtx, zone, name, powerdns.RRTypePtr(powerdns.RRType(recordType)))
+	endSpan(span, err)
+	metrics.observePowerDNSRequest("get", err)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Older PowerDNS versions ignore the name/type filter and return the whole zone
+	for _, rrset := range rrsets {
+		if rrset.Name == nil || rrset.Type == nil || RecordType(*rrset.Type) != recordType || normalizeName(*rrset.Name) != normalizeName(name) {
+			continue
+		}
+
+		var values []string
+		for _, record := range rrset.Records {
+			if record.Content != nil {
+				values = append(values, *record.Content)
+			}
+		}
+		return values, powerdns.Uint32Value(rrset.TTL), nil
+	}
+	return nil, 0, nil
+}
+
+// EnsureRecords replaces an RRset in PowerDNS, with the RECORD_COMMENT
+// comment if one is configured.
+func (t *PowerDNSTarget) EnsureRecords(ctx context.Context, zone, name string, recordType RecordType, ttl uint32, values []string) error {
+	// Let a started write complete even if shutdown begins meanwhile
+	spanCtx, span := startSpan(context.WithoutCancel(ctx), "powerdns.Change", recordSpanAttributes(zone, name, recordType)...)
+	err := t.Client.Records.Change(spanCtx, zone, name, powerdns.RRType(recordType), ttl, values, recordOptions(t.recordComment)...)
+	endSpan(span, err)
+	metrics.observePowerDNSRequest("change", err)
+	return err
+}
+
+// DeleteRecords deletes an RRset from PowerDNS.
+func (t *PowerDNSTarget) DeleteRecords(ctx context.Context, zone, name string, recordType RecordType) error {
+	spanCtx, span := startSpan(context.WithoutCancel(ctx), "powerdns.Delete", recordSpanAttributes(zone, name, recordType)...)
+	err := t.Client.Records.Delete(spanCtx, zone, name, powerdns.RRType(recordType))
+	endSpan(span, err)
+	metrics.observePowerDNSRequest("delete", err)
+	return err
+}
+
+// ListZones lists the zones of the PowerDNS server.
+func (t *PowerDNSTarget) ListZones(ctx context.Context) ([]string, error) {
+	zoneList, err := t.Client.Zones.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []string
+	for _, zone := range zoneList {
+		if zone.Name != nil {
+			zones = append(zones, *zone.Name)
+		}
+	}
+	return zones, nil
+}
+
+// ZoneSerial reads the SOA serial of a zone from PowerDNS.
+func (t *PowerDNSTarget) ZoneSerial(ctx context.Context, zone string) (uint32, error) {
+	spanCtx, span := startSpan(ctx, "powerdns.GetZone", attribute.String("dns.zone", zone))
+	result, err := t.Client.Zones.Get(spanCtx, zone)
+	endSpan(span, err)
+	metrics.observePowerDNSRequest("zone", err)
+	if err != nil {
+		return 0, err
+	}
+	return powerdns.Uint32Value(result.Serial), nil
+}
+
+// FinishZoneUpdate rectifies the zone and notifies its secondaries, as
+// configured, after its records changed.
+func (t *PowerDNSTarget) FinishZoneUpdate(ctx context.Context, options zoneFinishOptions, zone string) {
+	finishZoneUpdate(ctx, t, options, zone)
+}
+
+// verifyPowerDNSTargets checks that each target is reachable and serves the
+// configured zones. It fails only if no target passes.
+func verifyPowerDNSTargets(ctx context.Context, targets []*PowerDNSTarget, config *Config) error {
+	var errs []error
+	for _, target := range targets {
+		servers, err := target.Client.Servers.List(ctx)
+		if err != nil {
+			slog.Error("Failed to connect to PowerDNS API", "target", target.URL, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", target.URL, err))
+			continue
+		}
+		slog.Info("Connected to PowerDNS API", "target", target.URL, "servers", len(servers))
+
+		if !hasServerID(servers, target.Client.VHost) {
+			slog.Error("PowerDNS server id not found", "target", target.URL, "server_id", target.Client.VHost, "available", serverIDs(servers))
+			errs = append(errs, fmt.Errorf("%s: server id %q not found, available: %s (set POWERDNS_SERVER_ID)", target.URL, target.Client.VHost, strings.Join(serverIDs(servers), ", ")))
+			continue
+		}
+
+		var zoneErr error
+		for _, zoneConfig := range zoneConfigs(config) {
+			_, err := target.Client.Zones.Get(ctx, zoneConfig.DNSZone)
+			if config.CreateZone && isNotFound(err) {
+				err = createZone(ctx, target, config, zoneConfig.DNSZone)
+			}
+			if err != nil {
+				slog.Error("Failed to access DNS zone", "target", target.URL, "zone", zoneConfig.DNSZone, "error", err)
+				zoneErr = fmt.Errorf("%s: zone %s: %w", target.URL, zoneConfig.DNSZone, err)
+				break
+			}
+			slog.Info("Successfully verified DNS zone", "target", target.URL, "zone", zoneConfig.DNSZone)
+		}
+		if zoneErr != nil {
+			errs = append(errs, zoneErr)
+		}
+	}
+
+	if len(errs) == len(targets) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// hasServerID reports whether servers includes the server with the given id.
+func hasServerID(servers []powerdns.Server, id string) bool {
+	for _, server := range servers {
+		if server.ID != nil && *server.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// serverIDs returns the ids of servers.
+func serverIDs(servers []powerdns.Server) []string {
+	ids := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if server.ID != nil {
+			ids = append(ids, *server.ID)
+		}
+	}
+	return ids
+}
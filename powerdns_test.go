@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePowerDNSURLs(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"http://pdns1:8081", []string{"http://pdns1:8081"}},
+		{"http://pdns1:8081, http://pdns2:8081", []string{"http://pdns1:8081", "http://pdns2:8081"}},
+		{"http://pdns1:8081,,http://pdns1:8081,", []string{"http://pdns1:8081"}},
+		{" , ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parsePowerDNSURLs(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePowerDNSURLs(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigRequiresPowerDNSURL(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("POWERDNS_URL", " , ")
+
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("expected error for empty POWERDNS_URL list")
+	}
+}
+
+func TestLoadConfigPowerDNSTimeout(t *testing.T) {
+	setRequiredEnv(t)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.PowerDNSTimeout != DefaultPowerDNSTimeout {
+		t.Errorf("PowerDNSTimeout = %v, want default %v", config.PowerDNSTimeout, DefaultPowerDNSTimeout)
+	}
+
+	t.Setenv("POWERDNS_TIMEOUT", "2m")
+	if config, err = loadConfig(); err != nil || config.PowerDNSTimeout != 2*time.Minute {
+		t.Errorf("POWERDNS_TIMEOUT=2m: PowerDNSTimeout = %v, error = %v", config.PowerDNSTimeout, err)
+	}
+}
+
+func TestLoadConfigPowerDNSServerID(t *testing.T) {
+	tests := []struct {
+		serverID string
+		vhost    string
+		want     string
+	}{
+		{want: "localhost"},
+		{serverID: "tenant-a", want: "tenant-a"},
+		{vhost: "legacy", want: "legacy"},
+		{serverID: "tenant-a", vhost: "legacy", want: "tenant-a"},
+	}
+
+	for _, tt := range tests {
+		setRequiredEnv(t)
+		t.Setenv("POWERDNS_SERVER_ID", tt.serverID)
+		t.Setenv("POWERDNS_VHOST", tt.vhost)
+
+		config, err := loadConfig()
+		if err != nil {
+			t.Fatalf("loadConfig() error = %v", err)
+		}
+		if config.PowerDNSServerID != tt.want {
+			t.Errorf("POWERDNS_SERVER_ID=%q POWERDNS_VHOST=%q: PowerDNSServerID = %q, want %q", tt.serverID, tt.vhost, config.PowerDNSServerID, tt.want)
+		}
+	}
+}
+
+func TestVerifyPowerDNSTargetsServerID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/servers" {
+			w.Write([]byte(`[{"id": "localhost"}, {"id": "tenant-a"}]`))
+			return
+		}
+		w.Write([]byte(`{"name": "example.com."}`))
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		serverID string
+		wantErr  bool
+	}{
+		{"tenant-a", false},
+		{"tenant-b", true},
+	} {
+		config := &Config{PowerDNSURLs: []string{server.URL}, PowerDNSServerID: tt.serverID, DNSZone: "example.com.", DNSRecords: []string{"www.example.com."}}
+		targets, err := newPowerDNSTargets(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = verifyPowerDNSTargets(context.Background(), targets, config)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("server id %q: verifyPowerDNSTargets() error = %v, wantErr %v", tt.serverID, err, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "localhost, tenant-a") {
+			t.Errorf("error %q should list the available server ids", err)
+		}
+	}
+}
+
+func TestNewPowerDNSTargetsProxy(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("HTTPS_PROXY", "http://proxy.example:3128")
+	t.Setenv("NO_PROXY", "pdns-internal.example")
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://pdns.example:8081", "http://proxy.example:3128"},
+		{"http://pdns.example:8081", ""},
+		{"https://pdns-internal.example:8081", ""},
+	}
+	for _, tt := range tests {
+		targets, err := newPowerDNSTargets(&Config{PowerDNSURLs: []string{tt.url}, PowerDNSTimeout: 5 * time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		debug := targets[0].httpClient.Transport.(*userAgentTransport).base.(*debugTransport)
+		transport, ok := debug.base.(*http.Transport)
+		if !ok {
+			t.Fatalf("base transport is %T, want *http.Transport", debug.base)
+		}
+		if targets[0].httpClient.Timeout != 5*time.Second {
+			t.Errorf("%s: timeout = %v, want 5s", tt.url, targets[0].httpClient.Timeout)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, tt.url+"/api/v1/servers", nil)
+		proxy, err := transport.Proxy(req)
+		if err != nil {
+			t.Fatalf("%s: Proxy() error = %v", tt.url, err)
+		}
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if got != tt.want {
+			t.Errorf("%s: proxy = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNewPowerDNSTargetsUserAgent(t *testing.T) {
+	userAgents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": "localhost"}]`))
+	}))
+	defer server.Close()
+
+	for _, want := range []string{defaultUserAgent(), "custom-agent/1.0"} {
+		targets, err := newPowerDNSTargets(&Config{PowerDNSURLs: []string{server.URL}, PowerDNSServerID: "localhost", UserAgent: want})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := targets[0].Client.Servers.List(context.Background()); err != nil {
+			t.Fatalf("Servers.List() error = %v", err)
+		}
+		if got := <-userAgents; got != want {
+			t.Errorf("User-Agent = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestDebugTransportLogsRequests(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error": "Record www.example.com./A '203.0.113.1 ': Parsing record content"}`))
+	}))
+	defer server.Close()
+
+	targets, err := newPowerDNSTargets(&Config{PowerDNSURLs: []string{server.URL}, PowerDNSServerID: "localhost", PowerDNSAPIKey: "secret-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, level := range []slog.Level{slog.LevelInfo, slog.LevelDebug} {
+		var logs bytes.Buffer
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: level})))
+
+		err := targets[0].EnsureRecords(context.Background(), "example.com.", "www.example.com.", RecordTypeA, 300, []string{"203.0.113.1"})
+		if err == nil {
+			t.Fatal("EnsureRecords() should fail on a 422 response")
+		}
+		if !strings.Contains(err.Error(), "Parsing record content") {
+			t.Errorf("EnsureRecords() error = %v, want the PowerDNS error message", err)
+		}
+
+		output := logs.String()
+		if level == slog.LevelInfo {
+			if output != "" {
+				t.Errorf("logs at info level = %q, want none", output)
+			}
+			continue
+		}
+		for _, want := range []string{"method=PATCH", "/api/v1/servers/localhost/zones/example.com.", `\"content\":\"203.0.113.1\"`, "status=422", "Parsing record content", redactedValue} {
+			if !strings.Contains(output, want) {
+				t.Errorf("debug logs missing %q:\n%s", want, output)
+			}
+		}
+		if strings.Contains(output, "secret-key") {
+			t.Errorf("debug logs contain the API key:\n%s", output)
+		}
+	}
+}
+
+func TestLoggedBodyTruncates(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), maxLoggedBodySize+10)
+	got := loggedBody(body)
+	if !strings.HasPrefix(got, strings.Repeat("x", maxLoggedBodySize)+"...") || !strings.HasSuffix(got, "(10 bytes truncated)") {
+		t.Errorf("loggedBody() = ...%q, want the body cut after %d bytes", got[len(got)-30:], maxLoggedBodySize)
+	}
+}
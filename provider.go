@@ -0,0 +1,76 @@
+package main
+
+import "context"
+
+// RecordType is a DNS record type such as "A" or "AAAA". The sync logic uses
+// it rather than a backend's own type, which each provider converts to.
+type RecordType string
+
+// Record types managed by the controller.
+const (
+	RecordTypeA     RecordType = "A"
+	RecordTypeAAAA  RecordType = "AAAA"
+	RecordTypeCNAME RecordType = "CNAME"
+	RecordTypeNS    RecordType = "NS"
+	RecordTypePTR   RecordType = "PTR"
+	RecordTypeTXT   RecordType = "TXT"
+)
+
+// DNSProvider is a DNS backend that records are published to. The sync logic
+// only talks to backends through this interface, so another provider needs
+// nothing but an implementation of it; PowerDNSTarget is the PowerDNS one.
+type DNSProvider interface {
+	// Name identifies the backend in logs and errors.
+	Name() string
+
+	// GetRecords returns the values and TTL of an RRset, or no values if
+	// it does not exist.
+	GetRecords(ctx context.Context, zone, name string, recordType RecordType) ([]string, uint32, error)
+
+	// EnsureRecords replaces the RRset with the given TTL and values,
+	// creating it if needed.
+	EnsureRecords(ctx context.Context, zone, name string, recordType RecordType, ttl uint32, values []string) error
+
+	// DeleteRecords removes the RRset.
+	DeleteRecords(ctx context.Context, zone, name string, recordType RecordType) error
+
+	// ListZones returns the names of the zones the backend hosts.
+	ListZones(ctx context.Context) ([]string, error)
+}
+
+// zoneFinisher is implemented by providers that act on a zone after its
+// records changed, such as PowerDNS rectifying it and notifying secondaries.
+type zoneFinisher interface {
+	FinishZoneUpdate(ctx context.Context, options zoneFinishOptions, zone string)
+}
+
+// zoneFinishOptions are the settings a zoneFinisher acts on.
+type zoneFinishOptions struct {
+	rectify bool // RECTIFY_ZONE
+	notify  bool // NOTIFY_SECONDARIES
+	dryRun  bool // Only log what would be done
+}
+
+// finishOptions returns the zone finishing settings of config.
+func finishOptions(config *Config) zoneFinishOptions {
+	return zoneFinishOptions{
+		rectify: config.RectifyZone,
+		notify:  config.NotifySecondaries,
+		dryRun:  config.DryRun,
+	}
+}
+
+// zoneSerialer is implemented by providers that can report the SOA serial of
+// a zone, which is logged around the changes of a sync.
+type zoneSerialer interface {
+	ZoneSerial(ctx context.Context, zone string) (uint32, error)
+}
+
+// dnsProviders returns the targets as providers.
+func dnsProviders(targets []*PowerDNSTarget) []DNSProvider {
+	providers := make([]DNSProvider, len(targets))
+	for i, target := range targets {
+		providers[i] = target
+	}
+	return providers
+}
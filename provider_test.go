@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// memoryProvider is a DNSProvider keeping RRsets in a map, showing that the
+// sync logic works without PowerDNS.
+type memoryProvider struct {
+	rrsets map[string][]string
+	zones  []string
+}
+
+func (p *memoryProvider) Name() string { return "memory" }
+
+func (p *memoryProvider) GetRecords(ctx context.Context, zone, name string, recordType RecordType) ([]string, uint32, error) {
+	values, ok := p.rrsets[name+" "+string(recordType)]
+	if !ok {
+		return nil, 0, nil
+	}
+	return values, DefaultTTL, nil
+}
+
+func (p *memoryProvider) EnsureRecords(ctx context.Context, zone, name string, recordType RecordType, ttl uint32, values []string) error {
+	p.rrsets[name+" "+string(recordType)] = values
+	return nil
+}
+
+func (p *memoryProvider) DeleteRecords(ctx context.Context, zone, name string, recordType RecordType) error {
+	delete(p.rrsets, name+" "+string(recordType))
+	return nil
+}
+
+func (p *memoryProvider) ListZones(ctx context.Context) ([]string, error) {
+	return p.zones, nil
+}
+
+func TestUpdateDNSRecordsCustomProvider(t *testing.T) {
+	provider := &memoryProvider{
+		rrsets: map[string][]string{"www.example.com. AAAA": {"2001:db8::1"}},
+		zones:  []string{"example.com.", "2.0.192.in-addr.arpa."},
+	}
+	config := newUpdateTestConfig()
+	config.ManagePTR = true
+	ips, _ := parseIPAddresses("192.0.2.1")
+
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{provider}, config, ips); err != nil {
+		t.Fatalf("updateDNSRecords() error = %v", err)
+	}
+
+	if got := strings.Join(provider.rrsets["www.example.com. A"], ","); got != "192.0.2.1" {
+		t.Errorf("A record = %s, want 192.0.2.1", got)
+	}
+	if _, ok := provider.rrsets["www.example.com. AAAA"]; ok {
+		t.Error("AAAA record should have been deleted")
+	}
+	if got := strings.Join(provider.rrsets["1.2.0.192.in-addr.arpa. PTR"], ","); got != "www.example.com." {
+		t.Errorf("PTR record = %s, want www.example.com.", got)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PTRIndexPrefix is prepended to the first DNS record to name the TXT record
+// listing the PTR records published by MANAGE_PTR.
+const PTRIndexPrefix = "_k8s-external-ip-ptr."
+
+// reverseName returns the PTR owner name of ip, e.g. 4.3.2.1.in-addr.arpa.
+// for 1.2.3.4, or the nibble format under ip6.arpa. for IPv6.
+func reverseName(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+
+	ip16 := ip.To16()
+	const hexDigits = "0123456789abcdef"
+	var b strings.Builder
+	for i := len(ip16) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[ip16[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[ip16[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String()
+}
+
+// findReverseZone returns the most specific zone in zones containing name, or
+// an empty string if none of them does.
+func findReverseZone(name string, zones []string) string {
+	best := ""
+	for _, zone := range zones {
+		zone = validateDNSZone(zone)
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(best) {
+			best = zone
+		}
+	}
+	return best
+}
+
+// updatePTRRecords points the PTR record of every published IP at the primary
+// DNS record, or at the DNS_RECORD_A or DNS_RECORD_AAAA name holding its
+// family, so that the reverse name resolves back to the IP. The IPs are the
+// A and AAAA values as published, so IPs that MAX_IPS_PER_RECORD left out get
+// no PTR record either. IPs whose reverse
+// zone is not hosted on this PowerDNS server are logged and skipped so they
+// don't fail the whole sync. The PTR names published are listed in a TXT
+// index like the per-node records, so that the PTRs of IPs that are no longer
+// published are deleted.
+func updatePTRRecords(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, ipv4Records, ipv6Records []string) {
+	zone := validateDNSZone(config.DNSZone)
+	indexValues, _, _, err := getRecordValues(ctx, provider, config, zone, ptrIndexName(config), RecordTypeTXT)
+	if err != nil {
+		slog.Warn("Failed to read PTR record index", "error", err)
+		return
+	}
+	previous := parsePerNodeIndex(indexValues)
+	if len(ipv4Records) == 0 && len(ipv6Records) == 0 && len(previous) == 0 {
+		return
+	}
+
+	zones, err := provider.ListZones(ctx)
+	if err != nil {
+		slog.Warn("Failed to list zones for PTR management", "error", err)
+		return
+	}
+
+	ipv4Target, ipv6Target := config.DNSRecords[0], config.DNSRecords[0]
+	if config.DNSRecordA != "" {
+		ipv4Target = config.DNSRecordA
+	}
+	if config.DNSRecordAAAA != "" {
+		ipv6Target = config.DNSRecordAAAA
+	}
+	desired := make(map[string]string, len(ipv4Records)+len(ipv6Records))
+	for _, value := range ipv4Records {
+		desired[reverseName(net.ParseIP(value))] = ipv4Target
+	}
+	for _, value := range ipv6Records {
+		desired[reverseName(net.ParseIP(value))] = ipv6Target
+	}
+
+	// List the new names before writing them, so that they are cleaned up
+	// even if the sync stops halfway
+	published := make(map[string]bool, len(desired)+len(previous))
+	for name := range desired {
+		published[name] = true
+	}
+	for _, name := range previous {
+		published[name] = true
+	}
+	if err := writePTRIndex(ctx, collector, provider, config, zone, published); err != nil {
+		slog.Warn("Failed to update PTR records", "error", err)
+		return
+	}
+
+	for _, name := range sortedNames(published) {
+		reverseZone := findReverseZone(name, zones)
+		target, ok := desired[name]
+		if reverseZone == "" {
+			if ok {
+				slog.Warn("No reverse zone is hosted on this PowerDNS server, skipping PTR record", "record", name)
+			}
+			delete(published, name)
+			continue
+		}
+
+		if !ok {
+			if deleteStalePTR(ctx, collector, provider, config, reverseZone, name) {
+				delete(published, name)
+			}
+			continue
+		}
+
+		slog.Info("Updating PTR record", "record", name, "zone", reverseZone, "target", target)
+		if err := changeRecord(ctx, collector, provider, config, reverseZone, name, RecordTypePTR, []string{target}); err != nil {
+			slog.Warn("Failed to update PTR record", "record", name, "error", err)
+		}
+	}
+
+	if err := writePTRIndex(ctx, collector, provider, config, zone, published); err != nil {
+		slog.Warn("Failed to update PTR records", "error", err)
+	}
+}
+
+// deleteStalePTR deletes the PTR record of an IP that is no longer published
+// if it still points at one of the controller's records, and reports whether
+// the name can be dropped from the index. A PTR that was changed to point
+// elsewhere is left alone.
+func deleteStalePTR(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone, name string) bool {
+	values, _, _, err := getRecordValues(ctx, provider, config, zone, name, RecordTypePTR)
+	if err != nil {
+		slog.Warn("Failed to read stale PTR record", "record", name, "error", err)
+		return false
+	}
+
+	targets := map[string]bool{normalizeName(config.DNSRecords[0]): true}
+	if config.DNSRecordA != "" {
+		targets[normalizeName(config.DNSRecordA)] = true
+	}
+	if config.DNSRecordAAAA != "" {
+		targets[normalizeName(config.DNSRecordAAAA)] = true
+	}
+	for _, value := range values {
+		if !targets[normalizeName(value)] {
+			slog.Info("PTR record of an IP no longer published points elsewhere, leaving it", "record", name, "values", values)
+			return true
+		}
+	}
+
+	slog.Info("IP of PTR record is no longer published, removing it", "record", name)
+	deleteRecord(ctx, collector, provider, config, zone, name, RecordTypePTR)
+	return true
+}
+
+// ptrIndexName returns the name of the TXT record listing the PTR records.
+func ptrIndexName(config *Config) string {
+	return PTRIndexPrefix + validateDNSRecord(config.DNSRecords[0])
+}
+
+// writePTRIndex stores the PTR record names, deleting the index once there
+// are none.
+func writePTRIndex(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone string, names map[string]bool) error {
+	var values []string
+	for _, name := range sortedNames(names) {
+		values = append(values, strconv.Quote(name))
+	}
+	if len(values) == 0 {
+		deleteRecord(ctx, collector, provider, config, zone, ptrIndexName(config), RecordTypeTXT)
+		return nil
+	}
+	if err := changeRecord(ctx, collector, provider, config, zone, ptrIndexName(config), RecordTypeTXT, values); err != nil {
+		return fmt.Errorf("failed to write PTR record index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{"192.0.2.10", "10.2.0.192.in-addr.arpa."},
+		{"2001:db8::567:89ab", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if result := reverseName(net.ParseIP(tt.ip)); result != tt.expected {
+				t.Errorf("reverseName(%s) = %s, want %s", tt.ip, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFindReverseZone(t *testing.T) {
+	zones := []string{"example.com.", "2.0.192.in-addr.arpa.", "192.in-addr.arpa", "8.b.d.0.1.0.0.2.ip6.arpa."}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"10.2.0.192.in-addr.arpa.", "2.0.192.in-addr.arpa."},
+		{"10.3.0.192.in-addr.arpa.", "192.in-addr.arpa."},
+		{"10.2.0.193.in-addr.arpa.", ""},
+		{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := findReverseZone(tt.name, zones); result != tt.expected {
+				t.Errorf("findReverseZone(%s) = %q, want %q", tt.name, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUpdateDNSRecordsPTRMaxIPsPerRecord(t *testing.T) {
+	provider := &memoryProvider{
+		rrsets: map[string][]string{},
+		zones:  []string{"example.com.", "2.0.192.in-addr.arpa."},
+	}
+	config := newUpdateTestConfig()
+	config.ManagePTR = true
+	config.MaxIPsPerRecord = 2
+
+	ips, _ := parseIPAddresses("192.0.2.1,192.0.2.2,192.0.2.3")
+	if _, err := updateDNSRecords(context.Background(), []DNSProvider{provider}, config, ips); err != nil {
+		t.Fatalf("updateDNSRecords() error = %v", err)
+	}
+
+	// Only the IPs published at the A record get a PTR record
+	for _, value := range provider.rrsets["www.example.com. A"] {
+		name := reverseName(net.ParseIP(value)) + " PTR"
+		if got := strings.Join(provider.rrsets[name], ","); got != "www.example.com." {
+			t.Errorf("%s = %s, want www.example.com.", name, got)
+		}
+	}
+	ptrs := 0
+	for key := range provider.rrsets {
+		if strings.HasSuffix(key, " PTR") {
+			ptrs++
+		}
+	}
+	if ptrs != 2 {
+		t.Errorf("%d PTR records published, want 2 for the capped A record", ptrs)
+	}
+}
+
+func TestUpdatePTRRecordsRemovesStale(t *testing.T) {
+	provider := &memoryProvider{
+		rrsets: map[string][]string{},
+		zones:  []string{"example.com.", "2.0.192.in-addr.arpa."},
+	}
+	config := newUpdateTestConfig()
+	config.ManagePTR = true
+
+	updatePTRRecords(context.Background(), newSyncCollector(), provider, config, []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}, nil)
+	for _, name := range []string{"1.2.0.192.in-addr.arpa. PTR", "2.2.0.192.in-addr.arpa. PTR", "3.2.0.192.in-addr.arpa. PTR"} {
+		if got := strings.Join(provider.rrsets[name], ","); got != "www.example.com." {
+			t.Fatalf("%s = %s, want www.example.com.", name, got)
+		}
+	}
+
+	// Someone repointed the PTR of 192.0.2.3, which is then no longer ours
+	provider.rrsets["3.2.0.192.in-addr.arpa. PTR"] = []string{"mail.example.com."}
+
+	updatePTRRecords(context.Background(), newSyncCollector(), provider, config, []string{"192.0.2.1"}, nil)
+	if _, ok := provider.rrsets["2.2.0.192.in-addr.arpa. PTR"]; ok {
+		t.Error("PTR record of the removed IP 192.0.2.2 should have been deleted")
+	}
+	if got := strings.Join(provider.rrsets["3.2.0.192.in-addr.arpa. PTR"], ","); got != "mail.example.com." {
+		t.Errorf("repointed PTR record = %s, want it left alone", got)
+	}
+	if got := strings.Join(provider.rrsets["_k8s-external-ip-ptr.www.example.com. TXT"], ","); got != `"1.2.0.192.in-addr.arpa."` {
+		t.Errorf("PTR index = %s, want only the published IP", got)
+	}
+
+	updatePTRRecords(context.Background(), newSyncCollector(), provider, config, nil, nil)
+	if _, ok := provider.rrsets["1.2.0.192.in-addr.arpa. PTR"]; ok {
+		t.Error("PTR record should have been deleted once no IPs are published")
+	}
+	if _, ok := provider.rrsets["_k8s-external-ip-ptr.www.example.com. TXT"]; ok {
+		t.Error("PTR index should have been deleted once empty")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// secretConfigFields are reported as changed on reload without their values,
+// and redacted in /config. Webhook URLs such as Slack's embed a token.
+var secretConfigFields = map[string]bool{
+	"PowerDNSAPIKey": true,
+	"WebhookURL":     true,
+	"SyncToken":      true,
+}
+
+// restartConfigFields only take effect at startup, so changing them on
+// reload is reported rather than silently ignored.
+var restartConfigFields = map[string]bool{
+	"MetricsAddr":    true,
+	"HealthAddr":     true,
+	"KubeConfig":     true,
+	"K8sMode":        true,
+	"RunOnce":        true,
+	"LeaderElection": true,
+	"LeaseNamespace": true,
+	"LeaseName":      true,
+	"ReadyFailures":  true,
+}
+
+// reloadConfig re-reads CONFIG_FILE and the other settings, including the
+// API key file, and builds new PowerDNS clients, checking that every target
+// serves the configured zones. On any error the running configuration stays
+// untouched. Environment variables and flags cannot change in a running
+// process, so only file-based settings actually change.
+func reloadConfig(ctx context.Context) (*Config, []DNSProvider, error) {
+	previous := fileValues
+	if path := getEnv("CONFIG_FILE"); path != "" {
+		values, err := loadConfigFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+		}
+		fileValues = values
+	}
+
+	config, targets, err := buildReloadedConfig(ctx)
+	if err != nil {
+		fileValues = previous
+		return nil, nil, err
+	}
+	return config, dnsProviders(targets), nil
+}
+
+func buildReloadedConfig(ctx context.Context) (*Config, []*PowerDNSTarget, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	targets, err := newPowerDNSTargets(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := verifyPowerDNSTargets(ctx, targets, config); err != nil {
+		return nil, nil, fmt.Errorf("no PowerDNS server is usable: %w", err)
+	}
+	return config, targets, nil
+}
+
+// configChanges describes the settings that differ between two configs, and
+// lists the changed settings that need a restart to take effect.
+func configChanges(old, new *Config) (changes, needRestart []string) {
+	oldValue := reflect.ValueOf(old).Elem()
+	newValue := reflect.ValueOf(new).Elem()
+	for i := 0; i < oldValue.NumField(); i++ {
+		name := oldValue.Type().Field(i).Name
+		before, after := oldValue.Field(i).Interface(), newValue.Field(i).Interface()
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		if secretConfigFields[name] {
+			changes = append(changes, name+" changed")
+		} else {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", name, before, after))
+		}
+		if restartConfigFields[name] {
+			needRestart = append(needRestart, name)
+		}
+	}
+	return changes, needRestart
+}
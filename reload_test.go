@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigChanges(t *testing.T) {
+	old := &Config{TTL: 300, PowerDNSAPIKey: "old-secret", MetricsAddr: ":9090"}
+	updated := &Config{TTL: 60, PowerDNSAPIKey: "new-secret", MetricsAddr: ":9091"}
+
+	changes, needRestart := configChanges(old, updated)
+	wantChanges := []string{"TTL: 300 -> 60", "MetricsAddr: :9090 -> :9091", "PowerDNSAPIKey changed"}
+	if len(changes) != len(wantChanges) {
+		t.Fatalf("configChanges() = %v, want %v in any order", changes, wantChanges)
+	}
+	for _, want := range wantChanges {
+		found := false
+		for _, change := range changes {
+			found = found || change == want
+		}
+		if !found {
+			t.Errorf("configChanges() = %v, missing %q", changes, want)
+		}
+	}
+	if !reflect.DeepEqual(needRestart, []string{"MetricsAddr"}) {
+		t.Errorf("needRestart = %v, want [MetricsAddr]", needRestart)
+	}
+
+	if changes, _ := configChanges(old, old); len(changes) != 0 {
+		t.Errorf("configChanges() of identical configs = %v, want none", changes)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/servers" {
+			w.Write([]byte(`[{"id": "localhost"}]`))
+			return
+		}
+		w.Write([]byte(`{"name": "example.com."}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("POWERDNS_URL", server.URL)
+	t.Setenv("CONFIG_FILE", path)
+	oldFileValues := fileValues
+	defer func() { fileValues = oldFileValues }()
+
+	writeConfig("dns_ttl: 1m\n")
+	config, targets, err := reloadConfig(context.Background())
+	if err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	if config.TTL != 60 || len(targets) != 1 {
+		t.Errorf("reloadConfig() TTL = %d with %d targets, want 60 and 1", config.TTL, len(targets))
+	}
+
+	writeConfig("dns_ttl: 1m\nunknown_setting: true\n")
+	if _, _, err := reloadConfig(context.Background()); err == nil {
+		t.Fatal("reloadConfig() should fail on an invalid config file")
+	}
+	if fileValues["DNS_TTL"] != "1m" {
+		t.Errorf("fileValues after failed reload = %v, want the previous values", fileValues)
+	}
+
+	writeConfig("dns_ttl: 2m\nip_family: ipv5\n")
+	if _, _, err := reloadConfig(context.Background()); err == nil {
+		t.Fatal("reloadConfig() should fail on an invalid setting")
+	}
+	if fileValues["DNS_TTL"] != "1m" {
+		t.Errorf("fileValues after rejected config = %v, want the previous values", fileValues)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultPowerDNSMaxRetries is how many times a failed PowerDNS request is retried.
+	DefaultPowerDNSMaxRetries = 3
+	retryBaseDelay            = 500 * time.Millisecond
+	retryMaxDelay             = 10 * time.Second
+
+	// DefaultStartupRetries is how many times a failed startup check is retried.
+	DefaultStartupRetries = 5
+	// DefaultStartupRetryInterval is the wait between startup check attempts.
+	DefaultStartupRetryInterval = 5 * time.Second
+)
+
+// retryPowerDNS runs fn until it succeeds, fails with a non-retryable error,
+// or maxRetries retries have been used. Waits between attempts grow
+// exponentially with jitter and are aborted when ctx is cancelled.
+func retryPowerDNS(ctx context.Context, maxRetries int, description string, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || !isRetryable(err) || attempt >= maxRetries {
+			return err
+		}
+
+		delay := retryDelay(attempt)
+		slog.Warn("PowerDNS request failed, retrying", "operation", description, "attempt", attempt+1, "max_attempts", maxRetries+1, "delay", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryStartup runs a startup check until it succeeds or retries retries
+// have been used, waiting interval between attempts, so that dependencies
+// starting at the same time don't make the pod crash-loop. It gives up early
+// when ctx is cancelled.
+func retryStartup(ctx context.Context, retries int, interval time.Duration, description string, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		slog.Warn("Startup check failed, retrying", "check", description, "attempt", attempt+1, "max_attempts", retries+1, "delay", interval, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// retryDelay returns the exponential backoff for the given attempt with
+// jitter applied, capped at retryMaxDelay.
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	// Full jitter between half and the whole delay to spread out retries
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryable reports whether err is a transient failure: a network error or
+// a 5xx response. Client errors such as 404 or 422 are never retried.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if _, ok := powerDNSStatus(err); ok {
+		return isServerError(err)
+	}
+
+	// Anything else comes from the transport, e.g. connection refused
+	return true
+}
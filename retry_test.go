@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "Server error", err: &powerdns.Error{StatusCode: 503}, expected: true},
+		{name: "Not found", err: &powerdns.Error{StatusCode: 404}, expected: false},
+		{name: "Unprocessable entity", err: &powerdns.Error{StatusCode: 422}, expected: false},
+		{name: "Network error", err: errors.New("dial tcp: connection refused"), expected: true},
+		{name: "Context cancelled", err: context.Canceled, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isRetryable(tt.err); result != tt.expected {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := retryDelay(attempt)
+		if delay <= 0 || delay > retryMaxDelay {
+			t.Errorf("retryDelay(%d) = %v, want within (0, %v]", attempt, delay, retryMaxDelay)
+		}
+	}
+}
+
+func TestRetryPowerDNS(t *testing.T) {
+	t.Run("Stops on non-retryable error", func(t *testing.T) {
+		calls := 0
+		err := retryPowerDNS(context.Background(), 3, "test", func() error {
+			calls++
+			return &powerdns.Error{StatusCode: 422}
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("expected a single failed call, got %d calls and err=%v", calls, err)
+		}
+	})
+
+	t.Run("Aborts when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := 0
+		start := time.Now()
+		err := retryPowerDNS(ctx, 3, "test", func() error {
+			calls++
+			return &powerdns.Error{StatusCode: 500}
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("expected a single failed call, got %d calls and err=%v", calls, err)
+		}
+		if time.Since(start) > retryBaseDelay {
+			t.Errorf("retry did not abort promptly on cancelled context")
+		}
+	})
+
+	t.Run("Succeeds after transient failure", func(t *testing.T) {
+		calls := 0
+		err := retryPowerDNS(context.Background(), 3, "test", func() error {
+			calls++
+			if calls == 1 {
+				return &powerdns.Error{StatusCode: 502}
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("expected success on second call, got %d calls and err=%v", calls, err)
+		}
+	})
+}
+
+func TestRetryStartup(t *testing.T) {
+	calls := 0
+	err := retryStartup(context.Background(), 2, time.Millisecond, "test", func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("powerdns starting")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("retryStartup() = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err = retryStartup(context.Background(), 1, time.Millisecond, "test", func() error {
+		calls++
+		return errors.New("still down")
+	})
+	if err == nil || calls != 2 {
+		t.Errorf("retryStartup() = %v after %d calls, want failure after 2", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = retryStartup(ctx, 5, time.Hour, "test", func() error { return errors.New("down") })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("retryStartup() with cancelled context = %v, want context.Canceled", err)
+	}
+}
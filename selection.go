@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Strategies choosing which IPs are kept when MAX_IPS_PER_RECORD caps a record.
+const (
+	SelectionStable     = "stable"      // Always the first IPs in sorted order
+	SelectionRandom     = "random"      // A random subset, drawn anew on every sync
+	SelectionRoundRobin = "round-robin" // A window moving through the sorted IPs with every sync
+)
+
+// selectionRound counts the successful syncs, to move the round-robin window.
+// It is kept in memory only, so a restart starts over at the first IPs.
+var selectionRound int
+
+// parseSelectionStrategy validates an IP_SELECTION_STRATEGY value.
+func parseSelectionStrategy(value string) (string, error) {
+	switch value {
+	case SelectionStable, SelectionRandom, SelectionRoundRobin:
+		return value, nil
+	}
+	return "", fmt.Errorf("must be one of %s, %s or %s", SelectionStable, SelectionRandom, SelectionRoundRobin)
+}
+
+// selectRecordValues returns the limit values of values to publish according
+// to strategy, in their original order, and the values left out. values must
+// be sorted so that the stable and round-robin choices are deterministic.
+func selectRecordValues(strategy string, values []string, limit, round int) (kept, dropped []string) {
+	if limit <= 0 || len(values) <= limit {
+		return values, nil
+	}
+
+	var chosen []int
+	switch strategy {
+	case SelectionRandom:
+		chosen = rand.Perm(len(values))[:limit]
+	case SelectionRoundRobin:
+		start := (round * limit) % len(values)
+		for i := 0; i < limit; i++ {
+			chosen = append(chosen, (start+i)%len(values))
+		}
+	default:
+		for i := 0; i < limit; i++ {
+			chosen = append(chosen, i)
+		}
+	}
+
+	selected := make(map[int]bool, limit)
+	for _, i := range chosen {
+		selected[i] = true
+	}
+	for i, value := range values {
+		if selected[i] {
+			kept = append(kept, value)
+		} else {
+			dropped = append(dropped, value)
+		}
+	}
+	return kept, dropped
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectRecordValues(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		name        string
+		strategy    string
+		limit       int
+		round       int
+		wantKept    string
+		wantDropped string
+	}{
+		{"No limit", SelectionStable, 0, 0, "a,b,c,d,e", ""},
+		{"Under limit", SelectionRoundRobin, 5, 3, "a,b,c,d,e", ""},
+		{"Stable", SelectionStable, 2, 7, "a,b", "c,d,e"},
+		{"Round-robin first round", SelectionRoundRobin, 2, 0, "a,b", "c,d,e"},
+		{"Round-robin second round", SelectionRoundRobin, 2, 1, "c,d", "a,b,e"},
+		{"Round-robin wraps around", SelectionRoundRobin, 2, 2, "a,e", "b,c,d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := selectRecordValues(tt.strategy, values, tt.limit, tt.round)
+			if got := strings.Join(kept, ","); got != tt.wantKept {
+				t.Errorf("kept = %s, want %s", got, tt.wantKept)
+			}
+			if got := strings.Join(dropped, ","); got != tt.wantDropped {
+				t.Errorf("dropped = %s, want %s", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestSelectRecordValuesRoundRobinCoversAll(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e", "f", "g"}
+	published := make(map[string]bool)
+	for round := 0; round < 4; round++ {
+		kept, _ := selectRecordValues(SelectionRoundRobin, values, 2, round)
+		for _, value := range kept {
+			published[value] = true
+		}
+	}
+	if len(published) != len(values) {
+		t.Errorf("published %d of %d values over 4 rounds", len(published), len(values))
+	}
+}
+
+func TestSelectRecordValuesRandom(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e"}
+	kept, dropped := selectRecordValues(SelectionRandom, values, 3, 0)
+	if len(kept) != 3 || len(dropped) != 2 {
+		t.Fatalf("kept %v and dropped %v, want 3 and 2 values", kept, dropped)
+	}
+	seen := make(map[string]bool)
+	for _, value := range append(kept, dropped...) {
+		seen[value] = true
+	}
+	if len(seen) != len(values) {
+		t.Errorf("kept %v and dropped %v do not partition %v", kept, dropped, values)
+	}
+}
+
+func TestParseSelectionStrategy(t *testing.T) {
+	for _, value := range []string{SelectionStable, SelectionRandom, SelectionRoundRobin} {
+		if _, err := parseSelectionStrategy(value); err != nil {
+			t.Errorf("parseSelectionStrategy(%q) error = %v", value, err)
+		}
+	}
+	if _, err := parseSelectionStrategy("weighted"); err == nil {
+		t.Error("parseSelectionStrategy(\"weighted\") should fail")
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// SelfTestPrefix is prepended to the first DNS record to name the canary.
+const SelfTestPrefix = "_k8s-external-ip-selftest."
+
+// runSelfTest writes a TXT canary next to the first DNS record, reads it
+// back and deletes it again, so write-permission problems surface at startup.
+func runSelfTest(ctx context.Context, provider DNSProvider, config *Config) error {
+	zone := validateDNSZone(config.DNSZone)
+	name := SelfTestPrefix + validateDNSRecord(config.DNSRecords[0])
+	value := strconv.Quote("selftest-" + strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	if err := provider.EnsureRecords(ctx, zone, name, RecordTypeTXT, uint32(config.TTL), []string{value}); err != nil {
+		return fmt.Errorf("writing canary %s: %w", name, err)
+	}
+
+	values, _, found, err := getRecordValues(ctx, provider, config, zone, name, RecordTypeTXT)
+	if err != nil {
+		return fmt.Errorf("reading canary %s: %w", name, err)
+	}
+	if !found || !recordValuesEqual(values, []string{value}) {
+		return fmt.Errorf("canary %s read back as %v, want %s", name, values, value)
+	}
+
+	if err := provider.DeleteRecords(ctx, zone, name, RecordTypeTXT); err != nil {
+		return fmt.Errorf("deleting canary %s: %w", name, err)
+	}
+
+	slog.Info("Startup self-test passed", "canary", name)
+	return nil
+}
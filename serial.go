@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// serialReading is a serial read before a change, or the error reading it.
+type serialReading struct {
+	serial uint32
+	err    error
+}
+
+// noteZoneSerial reads the zone's serial into the collector before the first
+// write to it by the provider in the current updateDNSRecords call. Serials
+// are only read around actual writes, so syncs that change nothing cost no
+// extra API calls.
+func noteZoneSerial(ctx context.Context, collector *syncCollector, provider DNSProvider, zone string) {
+	serialer, ok := provider.(zoneSerialer)
+	if !ok {
+		return
+	}
+	key := zoneSerialKey{target: provider.Name(), zone: zone}
+	if _, seen := collector.serials[key]; seen {
+		return
+	}
+	serial, err := serialer.ZoneSerial(ctx, zone)
+	collector.serials[key] = serialReading{serial: serial, err: err}
+}
+
+// reportZoneSerials reads the serials of the zones the provider changed
+// again, logs how they moved and updates the powerdns_zone_serial gauge.
+func reportZoneSerials(ctx context.Context, collector *syncCollector, provider DNSProvider) {
+	serialer, ok := provider.(zoneSerialer)
+	if !ok {
+		return
+	}
+
+	var zones []string
+	for key := range collector.serials {
+		if key.target == provider.Name() {
+			zones = append(zones, key.zone)
+		}
+	}
+	sort.Strings(zones)
+
+	for _, zone := range zones {
+		before := collector.serials[zoneSerialKey{target: provider.Name(), zone: zone}]
+		after, err := serialer.ZoneSerial(ctx, zone)
+		if err != nil {
+			slog.Warn("Failed to read zone serial after changes", "target", provider.Name(), "zone", zone, "kind", powerDNSErrorKind(err), "error", err)
+			continue
+		}
+		metrics.setZoneSerial(provider.Name(), zone, after)
+
+		switch {
+		case before.err != nil:
+			slog.Info("Zone serial after changes", "target", provider.Name(), "zone", zone, "serial", after)
+		case after == before.serial:
+			// PowerDNS only bumps the serial with a SOA-EDIT-API zone setting
+			slog.Warn("Zone serial unchanged after changes, secondaries may not pick them up", "target", provider.Name(), "zone", zone, "serial", after)
+		default:
+			slog.Info("Zone serial changed", "target", provider.Name(), "zone", zone, "before", before.serial, "after", after)
+		}
+	}
+}
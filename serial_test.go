@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUpdateDNSRecordsZoneSerial(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	target := mock.target(t)
+	providers := []DNSProvider{target}
+	config := newUpdateTestConfig()
+	ips, _ := parseIPAddresses("192.0.2.1")
+
+	zoneReads := func() uint64 {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		return metrics.powerDNSRequests[powerDNSRequestKey{operation: "zone", result: "success"}]
+	}
+	serial := func() uint32 {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		return metrics.zoneSerials[zoneSerialKey{target: target.Name(), zone: "example.com."}]
+	}
+
+	reads := zoneReads()
+	if _, err := updateDNSRecords(context.Background(), providers, config, ips); err != nil {
+		t.Fatal(err)
+	}
+	if got := zoneReads() - reads; got != 2 {
+		t.Errorf("zone reads for a changing sync = %d, want 2 (before and after)", got)
+	}
+	if got := serial(); got != 1 {
+		t.Errorf("powerdns_zone_serial = %d, want 1", got)
+	}
+
+	reads = zoneReads()
+	if _, err := updateDNSRecords(context.Background(), providers, config, ips); err != nil {
+		t.Fatal(err)
+	}
+	if got := zoneReads() - reads; got != 0 {
+		t.Errorf("zone reads for an unchanged sync = %d, want 0", got)
+	}
+}
+
+func TestZoneSerialsPerCollector(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	target := mock.target(t)
+
+	zoneReads := func() uint64 {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		return metrics.powerDNSRequests[powerDNSRequestKey{operation: "zone", result: "success"}]
+	}
+
+	// Overlapping syncs each keep their own readings
+	writing, idle := newSyncCollector(), newSyncCollector()
+	noteZoneSerial(context.Background(), writing, target, "example.com.")
+	if _, ok := idle.serials[zoneSerialKey{target: target.Name(), zone: "example.com."}]; ok {
+		t.Error("serial noted by one collector leaked into another")
+	}
+
+	reads := zoneReads()
+	reportZoneSerials(context.Background(), idle, target)
+	if got := zoneReads() - reads; got != 0 {
+		t.Errorf("zone reads reporting a collector without writes = %d, want 0", got)
+	}
+	reportZoneSerials(context.Background(), writing, target)
+	if got := zoneReads() - reads; got != 1 {
+		t.Errorf("zone reads reporting a collector with a write = %d, want 1", got)
+	}
+}
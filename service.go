@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultServiceNamespace is used for IP_SOURCE=service when
+// SERVICE_NAMESPACE is unset.
+const DefaultServiceNamespace = "default"
+
+// parseIPSource validates a comma-separated IP_SOURCE value and returns it
+// lowercased with duplicates removed.
+func parseIPSource(value string) (string, error) {
+	var sources []string
+	seen := make(map[string]bool)
+	for _, source := range strings.Split(value, ",") {
+		source = strings.ToLower(strings.TrimSpace(source))
+		switch source {
+		case "":
+			continue
+		case IPSourceAnnotation, IPSourceStatus, IPSourceBoth, IPSourceService:
+		default:
+			return "", fmt.Errorf("invalid IP_SOURCE %q: must be a comma-separated list of %s, %s, %s or %s", value, IPSourceAnnotation, IPSourceStatus, IPSourceBoth, IPSourceService)
+		}
+		if !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return "", fmt.Errorf("invalid IP_SOURCE %q: no source given", value)
+	}
+	return strings.Join(sources, ","), nil
+}
+
+// hasIPSource reports whether the normalized IP_SOURCE value includes
+// source, with "both" standing for the annotation and status sources.
+func hasIPSource(configured, source string) bool {
+	for _, s := range strings.Split(configured, ",") {
+		if s == source {
+			return true
+		}
+		if s == IPSourceBoth && (source == IPSourceAnnotation || source == IPSourceStatus) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchServiceIPs returns the ingress IPs assigned to the configured
+// LoadBalancer Service. Hostname-only ingress entries are skipped.
+func fetchServiceIPs(ctx context.Context, clientset kubernetes.Interface, config *Config) ([]IPAddress, error) {
+	getCtx, cancel := context.WithTimeout(ctx, config.K8sTimeout)
+	defer cancel()
+
+	service, err := clientset.CoreV1().Services(config.ServiceNamespace).Get(getCtx, config.ServiceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service %s/%s: %w", config.ServiceNamespace, config.ServiceName, err)
+	}
+
+	var ips []IPAddress
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP == "" {
+			slog.Debug("Skipping load balancer ingress without an IP", "service", service.Name, "hostname", ingress.Hostname)
+			continue
+		}
+		ip := net.ParseIP(ingress.IP)
+		if ip == nil {
+			slog.Warn("Invalid load balancer ingress IP", "service", service.Name, "ip", ingress.IP)
+			continue
+		}
+		ips = append(ips, IPAddress{IP: ip, IsIPv6: ip.To4() == nil, String: ingress.IP})
+	}
+
+	slog.Info("Found load balancer ingress IPs", "service", config.ServiceNamespace+"/"+config.ServiceName, "count", len(ips))
+	return ips, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestParseIPSource(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "Both", want: "both"},
+		{value: "service", want: "service"},
+		{value: "annotation, Service,annotation", want: "annotation,service"},
+		{value: " , ", wantErr: true},
+		{value: "annotation,pods", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseIPSource(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIPSource(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseIPSource(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHasIPSource(t *testing.T) {
+	tests := []struct {
+		configured string
+		source     string
+		want       bool
+	}{
+		{"annotation", IPSourceAnnotation, true},
+		{"annotation", IPSourceService, false},
+		{"both", IPSourceStatus, true},
+		{"both", IPSourceService, false},
+		{"status,service", IPSourceService, true},
+		{"service", IPSourceAnnotation, false},
+	}
+
+	for _, tt := range tests {
+		if got := hasIPSource(tt.configured, tt.source); got != tt.want {
+			t.Errorf("hasIPSource(%q, %q) = %v, want %v", tt.configured, tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigServiceSource(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("IP_SOURCE", "service")
+
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("loadConfig() should require SERVICE_NAME for IP_SOURCE=service")
+	}
+
+	t.Setenv("SERVICE_NAME", "traefik")
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.ServiceNamespace != DefaultServiceNamespace || config.ServiceName != "traefik" {
+		t.Errorf("service = %s/%s, want %s/traefik", config.ServiceNamespace, config.ServiceName, DefaultServiceNamespace)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// recordOutcome is what a sync did to a single RRset.
+type recordOutcome int
+
+const (
+	outcomeCreated recordOutcome = iota
+	outcomeUpdated
+	outcomeUnchanged
+	outcomeDeleted
+)
+
+// RecordCounts tallies the outcomes of the RRsets of one record type.
+type RecordCounts struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+}
+
+// SyncSummary tallies what a sync did to the records, per record type. In
+// dry-run mode it counts the changes that would have been made.
+type SyncSummary struct {
+	Types map[RecordType]*RecordCounts
+}
+
+// newSyncSummary returns an empty summary.
+func newSyncSummary() *SyncSummary {
+	return &SyncSummary{Types: make(map[RecordType]*RecordCounts)}
+}
+
+// record counts one outcome for an RRset of the given type.
+func (s *SyncSummary) record(recordType RecordType, outcome recordOutcome) {
+	counts, ok := s.Types[recordType]
+	if !ok {
+		counts = &RecordCounts{}
+		s.Types[recordType] = counts
+	}
+	switch outcome {
+	case outcomeCreated:
+		counts.Created++
+	case outcomeUpdated:
+		counts.Updated++
+	case outcomeUnchanged:
+		counts.Unchanged++
+	case outcomeDeleted:
+		counts.Deleted++
+	}
+}
+
+// add adds the counts of other to s.
+func (s *SyncSummary) add(other *SyncSummary) {
+	for recordType, counts := range other.Types {
+		sum, ok := s.Types[recordType]
+		if !ok {
+			sum = &RecordCounts{}
+			s.Types[recordType] = sum
+		}
+		sum.Created += counts.Created
+		sum.Updated += counts.Updated
+		sum.Unchanged += counts.Unchanged
+		sum.Deleted += counts.Deleted
+	}
+}
+
+// Changed reports whether any record was created, updated or deleted.
+func (s *SyncSummary) Changed() bool {
+	return s.changes() > 0
+}
+
+// changes returns the number of records created, updated or deleted.
+func (s *SyncSummary) changes() int {
+	changes := 0
+	for _, counts := range s.Types {
+		changes += counts.Created + counts.Updated + counts.Deleted
+	}
+	return changes
+}
+
+// String formats the summary as one entry per record type, such as
+// "A: 1 created, 0 updated, 2 unchanged, 0 deleted", sorted by type.
+func (s *SyncSummary) String() string {
+	if len(s.Types) == 0 {
+		return "no records"
+	}
+
+	recordTypes := make([]string, 0, len(s.Types))
+	for recordType := range s.Types {
+		recordTypes = append(recordTypes, string(recordType))
+	}
+	sort.Strings(recordTypes)
+
+	parts := make([]string, 0, len(recordTypes))
+	for _, recordType := range recordTypes {
+		counts := s.Types[RecordType(recordType)]
+		parts = append(parts, fmt.Sprintf("%s: %d created, %d updated, %d unchanged, %d deleted",
+			recordType, counts.Created, counts.Updated, counts.Unchanged, counts.Deleted))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// rrsetKey identifies an RRset by name and type.
+type rrsetKey struct {
+	name       string
+	recordType RecordType
+}
+
+// syncCollector collects the outcomes of changeRecord and deleteRecord, the
+// values they left in each RRset, and the zone serials read before the first
+// writes, for one updateDNSRecords call. It is passed down to every write
+// rather than kept in a global, so that overlapping syncs never see each
+// other's state.
+type syncCollector struct {
+	outcomes map[rrsetKey]recordOutcome
+	values   map[rrsetKey][]string           // Values each RRset holds after the sync
+	changed  map[DNSProvider]bool            // Providers that had a record changed
+	serials  map[zoneSerialKey]serialReading // Serials before the first write, by provider and zone
+}
+
+// newSyncCollector returns an empty collector.
+func newSyncCollector() *syncCollector {
+	return &syncCollector{
+		outcomes: make(map[rrsetKey]recordOutcome),
+		values:   make(map[rrsetKey][]string),
+		changed:  make(map[DNSProvider]bool),
+		serials:  make(map[zoneSerialKey]serialReading),
+	}
+}
+
+// record notes the outcome of writing an RRset to provider, and the values
+// the RRset holds afterwards, none once it is deleted. Every provider gets
+// the same RRsets, so each one is counted once: as a change if any provider
+// changed it, else as unchanged. The values kept are those of the first
+// provider, which MERGE_RECORDS and NO_DELETE may have widened beyond the
+// desired ones.
+func (c *syncCollector) record(provider DNSProvider, recordName string, recordType RecordType, outcome recordOutcome, values []string) {
+	if outcome != outcomeUnchanged {
+		c.changed[provider] = true
+	}
+	key := rrsetKey{name: normalizeName(recordName), recordType: recordType}
+	if previous, seen := c.outcomes[key]; !seen || previous == outcomeUnchanged {
+		c.outcomes[key] = outcome
+	}
+	if _, seen := c.values[key]; !seen {
+		c.values[key] = values
+	}
+}
+
+// publishedValues returns the values the RRset holds after the sync, and
+// whether it was written at all.
+func (c *syncCollector) publishedValues(recordName string, recordType RecordType) ([]string, bool) {
+	values, ok := c.values[rrsetKey{name: normalizeName(recordName), recordType: recordType}]
+	return values, ok
+}
+
+// changedAt reports whether any record was changed at provider.
+func (c *syncCollector) changedAt(provider DNSProvider) bool {
+	return c.changed[provider]
+}
+
+// summary tallies the collected outcomes per record type.
+func (c *syncCollector) summary() *SyncSummary {
+	summary := newSyncSummary()
+	for key, outcome := range c.outcomes {
+		summary.record(key.recordType, outcome)
+	}
+	return summary
+}
+
+// lastSyncSummary is the summary of the latest sync, for POST /sync. It is
+// only used from the main goroutine.
+var lastSyncSummary *SyncSummary
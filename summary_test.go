@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSyncSummary(t *testing.T) {
+	summary := newSyncSummary()
+	if summary.Changed() || summary.String() != "no records" {
+		t.Errorf("empty summary = %q, changed %v", summary.String(), summary.Changed())
+	}
+
+	summary.record(RecordTypeAAAA, outcomeUnchanged)
+	summary.record(RecordTypeA, outcomeUnchanged)
+	summary.record(RecordTypeA, outcomeUnchanged)
+	if summary.Changed() {
+		t.Error("summary with only unchanged records should not report a change")
+	}
+
+	other := newSyncSummary()
+	other.record(RecordTypeA, outcomeCreated)
+	other.record(RecordTypeAAAA, outcomeDeleted)
+	other.record(RecordTypeTXT, outcomeUpdated)
+	summary.add(other)
+
+	if !summary.Changed() {
+		t.Error("summary with created records should report a change")
+	}
+	want := "A: 1 created, 0 updated, 2 unchanged, 0 deleted; " +
+		"AAAA: 0 created, 0 updated, 1 unchanged, 1 deleted; " +
+		"TXT: 0 created, 1 updated, 0 unchanged, 0 deleted"
+	if got := summary.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestSyncCollector(t *testing.T) {
+	first := &memoryProvider{}
+	second := &memoryProvider{}
+	collector := newSyncCollector()
+
+	// Each RRset counts once however many providers it is written to, as a
+	// change if any of them changed it
+	collector.record(first, "www.example.com.", RecordTypeA, outcomeUnchanged, nil)
+	collector.record(second, "WWW.example.com", RecordTypeA, outcomeUpdated, nil)
+	collector.record(first, "www.example.com.", RecordTypeAAAA, outcomeCreated, nil)
+	collector.record(second, "www.example.com.", RecordTypeAAAA, outcomeUnchanged, nil)
+	collector.record(first, "www.example.com.", RecordTypeTXT, outcomeUnchanged, nil)
+	collector.record(second, "www.example.com.", RecordTypeTXT, outcomeUnchanged, nil)
+
+	want := "A: 0 created, 1 updated, 0 unchanged, 0 deleted; " +
+		"AAAA: 1 created, 0 updated, 0 unchanged, 0 deleted; " +
+		"TXT: 0 created, 0 updated, 1 unchanged, 0 deleted"
+	if got := collector.summary().String(); got != want {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+	if !collector.changedAt(first) || !collector.changedAt(second) || collector.changedAt(&memoryProvider{}) {
+		t.Error("changedAt() should report exactly the providers that had a record changed")
+	}
+}
+
+func TestUpdateDNSRecordsCountsOncePerRRset(t *testing.T) {
+	providers := []DNSProvider{
+		&memoryProvider{rrsets: map[string][]string{}},
+		&memoryProvider{rrsets: map[string][]string{}},
+	}
+	config := newUpdateTestConfig()
+	ips, _ := parseIPAddresses("192.0.2.1,2001:db8::1")
+
+	summary, err := updateDNSRecords(context.Background(), providers, config, ips)
+	if err != nil {
+		t.Fatalf("updateDNSRecords() error = %v", err)
+	}
+	want := "A: 1 created, 0 updated, 0 unchanged, 0 deleted; AAAA: 1 created, 0 updated, 0 unchanged, 0 deleted"
+	if got := summary.String(); got != want {
+		t.Errorf("summary = %q, want %q with two providers", got, want)
+	}
+}
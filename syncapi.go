@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+// SyncTokenHeader carries the SYNC_TOKEN shared secret on POST /sync.
+const SyncTokenHeader = "X-Sync-Token"
+
+// syncRequests carries the syncs requested over HTTP to the controller loop,
+// which runs them between its periodic syncs so they never overlap.
+var syncRequests = make(chan syncRequest)
+
+// syncLoopRunning reports whether the controller loop is there to serve
+// syncRequests. It is not on a replica standing by for leadership, nor
+// before the initial sync has completed.
+var syncLoopRunning atomic.Bool
+
+// syncRequest asks the controller loop for an immediate sync, whose outcome
+// is sent on reply.
+type syncRequest struct {
+	reply chan syncResponse
+}
+
+// syncResponse is the JSON outcome of a sync requested on POST /sync.
+type syncResponse struct {
+	Changed bool                         `json:"changed"`
+	DryRun  bool                         `json:"dry_run"`
+	Records map[RecordType]*RecordCounts `json:"records"`
+	Summary string                       `json:"summary"`
+	Error   string                       `json:"error,omitempty"`
+}
+
+// newSyncResponse describes the outcome of a sync from its summary, which is
+// empty if the sync failed before writing any record.
+func newSyncResponse(summary *SyncSummary, dryRun bool, err error) syncResponse {
+	if summary == nil {
+		summary = newSyncSummary()
+	}
+	response := syncResponse{
+		Changed: summary.Changed(),
+		DryRun:  dryRun,
+		Records: summary.Types,
+		Summary: summary.String(),
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	return response
+}
+
+// syncHandler triggers a sync on POST and answers with its summary once it
+// has run. When SYNC_TOKEN is set, requests must carry it in the
+// X-Sync-Token header.
+func syncHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		config := effectiveConfig.Load()
+		if config == nil {
+			http.Error(w, "configuration not loaded", http.StatusServiceUnavailable)
+			return
+		}
+		if config.SyncToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(SyncTokenHeader)), []byte(config.SyncToken)) != 1 {
+			http.Error(w, "invalid or missing "+SyncTokenHeader+" header", http.StatusUnauthorized)
+			return
+		}
+
+		if !syncLoopRunning.Load() {
+			http.Error(w, "not syncing: this replica is starting or standing by for leadership", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Wait for the loop to pick the request up, which happens once any
+		// sync in progress has finished
+		request := syncRequest{reply: make(chan syncResponse, 1)}
+		select {
+		case syncRequests <- request:
+		case <-r.Context().Done():
+			return
+		}
+
+		var response syncResponse
+		select {
+		case response = <-request.reply:
+		case <-r.Context().Done():
+			return
+		}
+
+		status := http.StatusOK
+		if response.Error != "" {
+			status = http.StatusInternalServerError
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.Warn("Failed to write sync response", "error", err)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveSyncRequests answers every sync request with response until the test
+// ends, standing in for the running controller loop.
+func serveSyncRequests(t *testing.T, response syncResponse) {
+	done := make(chan struct{})
+	syncLoopRunning.Store(true)
+	t.Cleanup(func() {
+		syncLoopRunning.Store(false)
+		close(done)
+	})
+	go func() {
+		for {
+			select {
+			case request := <-syncRequests:
+				request.reply <- response
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func TestSyncHandler(t *testing.T) {
+	t.Cleanup(func() { effectiveConfig.Store(nil) })
+	effectiveConfig.Store(&Config{SyncToken: "s3cret"})
+
+	summary := newSyncSummary()
+	summary.record(RecordTypeA, outcomeUpdated)
+	serveSyncRequests(t, newSyncResponse(summary, false, nil))
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		want   int
+	}{
+		{"wrong method", http.MethodGet, "s3cret", http.StatusMethodNotAllowed},
+		{"missing token", http.MethodPost, "", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "guess", http.StatusUnauthorized},
+		{"valid token", http.MethodPost, "s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/sync", nil)
+			if tt.token != "" {
+				req.Header.Set(SyncTokenHeader, tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			syncHandler().ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var response syncResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if !response.Changed || response.Records[RecordTypeA] == nil || response.Records[RecordTypeA].Updated != 1 {
+				t.Errorf("response = %+v, want 1 updated A record", response)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerWithoutToken(t *testing.T) {
+	t.Cleanup(func() { effectiveConfig.Store(nil) })
+	effectiveConfig.Store(&Config{})
+	serveSyncRequests(t, newSyncResponse(nil, true, errors.New("failed to fetch external IPs")))
+
+	recorder := httptest.NewRecorder()
+	syncHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sync", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d for a failed sync", recorder.Code, http.StatusInternalServerError)
+	}
+	var response syncResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	if response.Error == "" || response.Changed || !response.DryRun || response.Summary != "no records" {
+		t.Errorf("response = %+v, want the error and an empty dry-run summary", response)
+	}
+}
+
+func TestSyncHandlerWithoutSyncLoop(t *testing.T) {
+	t.Cleanup(func() { effectiveConfig.Store(nil) })
+	effectiveConfig.Store(&Config{})
+
+	// Nothing reads syncRequests, as on a replica standing by for leadership
+	recorder := httptest.NewRecorder()
+	syncHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sync", nil))
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d without a running sync loop", recorder.Code, http.StatusServiceUnavailable)
+	}
+}
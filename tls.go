@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// buildTLSConfig returns the TLS settings for PowerDNS connections, or nil
+// to use Go's defaults when no client certificate, CA or verification
+// override is configured.
+func buildTLSConfig(config *Config) (*tls.Config, error) {
+	if config.PowerDNSClientCert == "" && config.PowerDNSCACert == "" && !config.PowerDNSInsecure {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.PowerDNSInsecure,
+	}
+
+	if config.PowerDNSClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.PowerDNSClientCert, config.PowerDNSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %s and key %s: %w", config.PowerDNSClientCert, config.PowerDNSClientKey, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.PowerDNSCACert != "" {
+		pem, err := os.ReadFile(config.PowerDNSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA certificate %s", config.PowerDNSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
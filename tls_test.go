@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM
+// files and returns their paths.
+func writeTestCertificate(t *testing.T) (certPath, keyPath string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "k8s-external-ip-powerdns-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath = filepath.Join(dir, "tls.crt")
+	keyPath = filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	certPath, keyPath := writeTestCertificate(t)
+
+	tlsConfig, err := buildTLSConfig(&Config{})
+	if err != nil || tlsConfig != nil {
+		t.Errorf("buildTLSConfig() without files = %v, %v, want nil, nil", tlsConfig, err)
+	}
+
+	tlsConfig, err = buildTLSConfig(&Config{PowerDNSClientCert: certPath, PowerDNSClientKey: keyPath, PowerDNSCACert: certPath})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error = %v", err)
+	}
+	if len(tlsConfig.Certificates) != 1 || tlsConfig.RootCAs == nil {
+		t.Errorf("buildTLSConfig() = %d certificates, RootCAs %v", len(tlsConfig.Certificates), tlsConfig.RootCAs)
+	}
+
+	if _, err := buildTLSConfig(&Config{PowerDNSClientCert: certPath, PowerDNSClientKey: certPath}); err == nil {
+		t.Error("buildTLSConfig() should fail when the key file holds no key")
+	}
+	if _, err := buildTLSConfig(&Config{PowerDNSCACert: keyPath}); err == nil {
+		t.Error("buildTLSConfig() should fail when the CA file holds no certificate")
+	}
+	if _, err := buildTLSConfig(&Config{PowerDNSCACert: filepath.Join(t.TempDir(), "missing.crt")}); err == nil {
+		t.Error("buildTLSConfig() should fail when the CA file is missing")
+	}
+}
+
+func TestLoadConfigRequiresCertAndKeyTogether(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("POWERDNS_CLIENT_CERT", "/etc/tls/tls.crt")
+
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should fail when POWERDNS_CLIENT_KEY is missing")
+	}
+}
+
+func TestBuildTLSConfigInsecureSkipVerify(t *testing.T) {
+	tlsConfig, err := buildTLSConfig(&Config{PowerDNSInsecure: true})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error = %v", err)
+	}
+	if tlsConfig == nil || !tlsConfig.InsecureSkipVerify {
+		t.Errorf("buildTLSConfig() = %+v, want InsecureSkipVerify", tlsConfig)
+	}
+}
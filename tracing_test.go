@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupTracingDisabledWithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	shutdown, err := setupTracing(context.Background())
+	if err != nil {
+		t.Fatalf("setupTracing() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	ctx, parent := startSpan(context.Background(), "syncDNSRecords")
+	_, child := startSpan(ctx, "powerdns.Change", recordSpanAttributes("example.com.", "cluster.example.com.", "A")...)
+	endSpan(child, errors.New("boom"))
+	endSpan(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	change, sync := spans[0], spans[1]
+	if change.Parent().SpanID() != sync.SpanContext().SpanID() {
+		t.Error("powerdns.Change span is not a child of the sync span")
+	}
+	if change.Status().Code != codes.Error || sync.Status().Code == codes.Error {
+		t.Errorf("span statuses = %v, %v, want the error only on the child", change.Status().Code, sync.Status().Code)
+	}
+	if len(change.Attributes()) != 3 {
+		t.Errorf("powerdns.Change attributes = %v, want zone, record and type", change.Attributes())
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultTXTAnnotation is the node annotation holding TXT values published
+// with MANAGE_TXT.
+const DefaultTXTAnnotation = "k3s.io/dns-txt"
+
+// maxTXTStringLength is the longest character-string a TXT record can hold;
+// longer values are split into several strings of one record.
+const maxTXTStringLength = 255
+
+// nodeTXTValues returns the TXT values of a node's annotation, one per
+// non-empty line.
+func nodeTXTValues(node *corev1.Node, annotationKey string) []string {
+	var values []string
+	for _, line := range strings.Split(node.Annotations[annotationKey], "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return values
+}
+
+// txtRecordValues returns the distinct TXT values of the nodes whose IPs are
+// published, in sorted order and quoted for PowerDNS.
+func txtRecordValues(ipAddresses []IPAddress) []string {
+	seen := make(map[string]bool)
+	for _, ip := range ipAddresses {
+		for _, value := range ip.TXT {
+			seen[value] = true
+		}
+	}
+
+	values := make([]string, 0, len(seen))
+	for _, value := range sortedNames(seen) {
+		values = append(values, quoteTXT(value))
+	}
+	return values
+}
+
+// quoteTXT returns value as TXT record content: quoted, with quotes and
+// backslashes escaped, and split into several strings if it exceeds the
+// 255 byte limit of one.
+func quoteTXT(value string) string {
+	var parts []string
+	for {
+		n := min(len(value), maxTXTStringLength)
+		// Don't cut a multi-byte character in half
+		for n < len(value) && n > 1 && !utf8.RuneStart(value[n]) {
+			n--
+		}
+		part := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value[:n])
+		parts = append(parts, `"`+part+`"`)
+		value = value[n:]
+		if value == "" {
+			return strings.Join(parts, " ")
+		}
+	}
+}
+
+// updateTXTRecord publishes the TXT values of the nodes at a record name,
+// deleting the RRset once no node has any.
+func updateTXTRecord(ctx context.Context, collector *syncCollector, provider DNSProvider, config *Config, zone, recordName string, values []string) error {
+	if config.NoDelete {
+		var err error
+		if values, err = keepExistingValues(ctx, provider, config, zone, recordName, RecordTypeTXT, values); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case len(values) > 0:
+		slog.Debug("Updating TXT record", "record", recordName, "values", len(values))
+		if err := changeRecord(ctx, collector, provider, config, zone, recordName, RecordTypeTXT, values); err != nil {
+			return fmt.Errorf("failed to update TXT record for %s: %w", recordName, err)
+		}
+	case config.NoDelete:
+		slog.Info("No TXT values found, but deletion is suppressed by NO_DELETE", "record", recordName)
+	default:
+		slog.Info("No TXT values found, deleting TXT record", "record", recordName)
+		deleteRecord(ctx, collector, provider, config, zone, recordName, RecordTypeTXT)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestQuoteTXT(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"site=fra1", `"site=fra1"`},
+		{`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{strings.Repeat("a", 255), `"` + strings.Repeat("a", 255) + `"`},
+		{strings.Repeat("a", 300), `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 45) + `"`},
+		{strings.Repeat("a", 254) + "é", `"` + strings.Repeat("a", 254) + `" "é"`},
+	}
+	for _, tt := range tests {
+		if got := quoteTXT(tt.value); got != tt.want {
+			t.Errorf("quoteTXT(%.20q...) = %.40q..., want %.40q...", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFetchExternalIPsTXT(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newTestNode("node-a", map[string]string{ExternalIPAnnotation: "203.0.113.1", DefaultTXTAnnotation: "site=fra1\n\nrack=3"}, nil),
+		newTestNode("node-b", map[string]string{ExternalIPAnnotation: "203.0.113.2", DefaultTXTAnnotation: "site=fra1"}, nil),
+		newTestNode("node-c", map[string]string{DefaultTXTAnnotation: "no-ips"}, nil),
+	)
+	config := newFetchTestConfig()
+	config.ManageTXT = true
+	config.TXTAnnotation = DefaultTXTAnnotation
+
+	ips, err := fetchExternalIPs(context.Background(), clientset, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`"rack=3"`, `"site=fra1"`}
+	if got := txtRecordValues(ips); !reflect.DeepEqual(got, want) {
+		t.Errorf("txtRecordValues() = %v, want %v", got, want)
+	}
+}
+
+func TestUpdateDNSRecordsTXT(t *testing.T) {
+	mock := newMockPowerDNS(t, "example.com.")
+	providers := []DNSProvider{mock.target(t)}
+	config := newUpdateTestConfig()
+	config.ManageTXT = true
+
+	ips, _ := parseIPAddresses("192.0.2.1")
+	ips[0].TXT = []string{"site=fra1"}
+	if _, err := updateDNSRecords(context.Background(), providers, config, ips); err != nil {
+		t.Fatal(err)
+	}
+	if got := mock.values("www.example.com.", RecordTypeTXT); !reflect.DeepEqual(got, []string{`"site=fra1"`}) {
+		t.Errorf("TXT values = %v, want the node's value", got)
+	}
+	mock.takeChanges()
+
+	ips[0].TXT = nil
+	if _, err := updateDNSRecords(context.Background(), providers, config, ips); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"DELETE TXT www.example.com."}
+	if got := mock.takeChanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestLoadConfigManageTXTWithOwnership(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("MANAGE_TXT", "true")
+	t.Setenv("TXT_OWNER_ID", "prod")
+
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() should reject MANAGE_TXT with TXT_OWNER_ID")
+	}
+}
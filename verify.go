@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VerifyTimeout bounds the lookups of one VERIFY_DNS check.
+const VerifyTimeout = 5 * time.Second
+
+// DefaultVerifyResolverPort is the port used for a VERIFY_RESOLVER given
+// without one.
+const DefaultVerifyResolverPort = "53"
+
+// ipResolver looks up the addresses of a name, like net.Resolver.
+type ipResolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// intendedRecord is the value set last published at a record, with the time
+// it was first published, so that a live answer older than the record's TTL
+// can be told apart from one a resolver may still be caching.
+type intendedRecord struct {
+	values string
+	since  time.Time
+}
+
+// intendedRecords remembers, for VERIFY_DNS, the intended value set of every
+// verified record keyed by name and type. Like lastPublishedIPs it is only
+// used from the main goroutine.
+var intendedRecords map[string]intendedRecord
+
+// newVerifyResolver returns the resolver VERIFY_DNS queries: the DNS server
+// at address, or the system resolver if address is empty.
+func newVerifyResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// parseVerifyResolver returns address as host:port, adding the DNS port if
+// it has none.
+func parseVerifyResolver(address string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), DefaultVerifyResolverPort)
+}
+
+// verifyLiveRecords resolves the A and AAAA records just published for the
+// zone and logs a warning for each one whose live answer differs from the
+// values the sync left in it, as collected by changeRecord and deleteRecord,
+// so that values kept by MERGE_RECORDS or NO_DELETE are expected too.
+// Records the sync did not write are skipped. A mismatch within the record's
+// TTL of the values changing is only logged at info level, as resolvers may
+// still be caching the old answer. Verification never fails the sync; it only
+// returns the name and type of each record reported as mismatched.
+func verifyLiveRecords(ctx context.Context, resolver ipResolver, collector *syncCollector, config *Config, now time.Time) []string {
+	if intendedRecords == nil {
+		intendedRecords = make(map[string]intendedRecord)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, VerifyTimeout)
+	defer cancel()
+
+	var mismatched []string
+	for _, c := range addressRecords(config) {
+		name := normalizeName(c.name)
+		key := name + " " + string(c.recordType)
+		published, written := collector.publishedValues(name, c.recordType)
+		if !written {
+			slog.Debug("Record was not written by this sync, skipping verification", "record", name, "type", c.recordType)
+			continue
+		}
+		want := sortedRecordValues(c.recordType, published)
+
+		intended, known := intendedRecords[key]
+		if !known || intended.values != strings.Join(want, ",") {
+			intended = intendedRecord{values: strings.Join(want, ","), since: now}
+			intendedRecords[key] = intended
+		}
+
+		got, err := lookupRecord(ctx, resolver, name, c.recordType)
+		if err != nil {
+			slog.Warn("Failed to verify record against live DNS", "record", name, "type", c.recordType, "error", err)
+			continue
+		}
+		if strings.Join(got, ",") == intended.values {
+			slog.Debug("Live DNS answer matches", "record", name, "type", c.recordType, "values", got)
+			continue
+		}
+
+		ttl := time.Duration(recordTTL(config, c.recordType)) * time.Second
+		if age := now.Sub(intended.since); age < ttl {
+			slog.Info("Live DNS answer differs, resolvers may still cache the previous values", "record", name, "type", c.recordType, "live", got, "intended", want, "changed_ago", age.Round(time.Second), "ttl", ttl)
+			continue
+		}
+		slog.Warn("Live DNS answer does not match the published record", "record", name, "type", c.recordType, "live", got, "intended", want)
+		mismatched = append(mismatched, key)
+	}
+	return mismatched
+}
+
+// lookupRecord returns the sorted, canonical values the resolver answers for
+// the A or AAAA record at name, or none if the name has no such record.
+func lookupRecord(ctx context.Context, resolver ipResolver, name string, recordType RecordType) ([]string, error) {
+	network := "ip4"
+	if recordType == RecordTypeAAAA {
+		network = "ip6"
+	}
+
+	ips, err := resolver.LookupIP(ctx, network, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.String())
+	}
+	return sortedRecordValues(recordType, values), nil
+}
+
+// sortedRecordValues returns the canonical values in order, for comparing
+// value sets.
+func sortedRecordValues(recordType RecordType, values []string) []string {
+	sorted := normalizeRecordValues(recordType, values)
+	sort.Strings(sorted)
+	return sorted
+}
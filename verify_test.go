@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers lookups from a map of "network name" to addresses.
+// Names without an entry do not exist, and names in failing return errors.
+type fakeResolver struct {
+	answers map[string][]string
+	failing map[string]bool
+}
+
+func (r *fakeResolver) LookupIP(_ context.Context, network, host string) ([]net.IP, error) {
+	if r.failing[host] {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+	}
+	values, ok := r.answers[network+" "+host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	ips := make([]net.IP, 0, len(values))
+	for _, value := range values {
+		ips = append(ips, net.ParseIP(value))
+	}
+	return ips, nil
+}
+
+// publishedCollector returns a collector in which a sync left ipv4 at the A
+// record and ipv6 at the AAAA record of www.example.com.
+func publishedCollector(ipv4, ipv6 []string) *syncCollector {
+	provider := &memoryProvider{}
+	collector := newSyncCollector()
+	collector.record(provider, "www.example.com.", RecordTypeA, outcomeUpdated, ipv4)
+	collector.record(provider, "www.example.com.", RecordTypeAAAA, outcomeUpdated, ipv6)
+	return collector
+}
+
+func TestVerifyLiveRecords(t *testing.T) {
+	t.Cleanup(func() { intendedRecords = nil })
+	config := newUpdateTestConfig()
+	config.TTL = 300
+	resolver := &fakeResolver{answers: map[string][]string{
+		"ip4 www.example.com.": {"203.0.113.2", "203.0.113.1"},
+	}}
+	now := time.Now()
+
+	// The live answer matches in any order, and a missing AAAA record
+	// matches a deleted one
+	if got := verifyLiveRecords(context.Background(), resolver, publishedCollector([]string{"203.0.113.1", "203.0.113.2"}, nil), config, now); len(got) != 0 {
+		t.Errorf("verifyLiveRecords() = %v, want no mismatches", got)
+	}
+
+	// A change is not reported while resolvers may still cache the old answer
+	collector := publishedCollector([]string{"203.0.113.1", "203.0.113.3"}, nil)
+	if got := verifyLiveRecords(context.Background(), resolver, collector, config, now.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("verifyLiveRecords() = %v, want no mismatches within the TTL", got)
+	}
+	got := verifyLiveRecords(context.Background(), resolver, collector, config, now.Add(7*time.Minute))
+	if want := []string{"www.example.com. A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("verifyLiveRecords() = %v, want %v once the TTL has passed", got, want)
+	}
+}
+
+func TestVerifyLiveRecordsFamilyNames(t *testing.T) {
+	t.Cleanup(func() { intendedRecords = nil })
+	config := newUpdateTestConfig()
+	config.DNSRecordAAAA = "v6.example.com."
+	resolver := &fakeResolver{
+		answers: map[string][]string{
+			"ip4 www.example.com.": {"203.0.113.1"},
+			"ip6 www.example.com.": {"2001:db8::1"},
+		},
+		failing: map[string]bool{"v6.example.com.": true},
+	}
+
+	provider := &memoryProvider{}
+	collector := newSyncCollector()
+	collector.record(provider, "www.example.com.", RecordTypeA, outcomeUpdated, []string{"203.0.113.1"})
+	collector.record(provider, "v6.example.com.", RecordTypeAAAA, outcomeUpdated, []string{"2001:db8::1"})
+
+	// The AAAA record lives at its own name, so the IPv6 address at the
+	// shared name is not checked, and a failed lookup is not a mismatch
+	if got := verifyLiveRecords(context.Background(), resolver, collector, config, time.Now()); len(got) != 0 {
+		t.Errorf("verifyLiveRecords() = %v, want no mismatches", got)
+	}
+	if _, ok := intendedRecords["v6.example.com. AAAA"]; !ok {
+		t.Errorf("intendedRecords = %v, want the DNS_RECORD_AAAA name checked", intendedRecords)
+	}
+	if _, ok := intendedRecords["www.example.com. AAAA"]; ok {
+		t.Errorf("intendedRecords = %v, want no AAAA check at the shared name", intendedRecords)
+	}
+}
+
+func TestVerifyLiveRecordsKeptValues(t *testing.T) {
+	t.Cleanup(func() { intendedRecords = nil })
+	config := newUpdateTestConfig()
+	config.NoDelete = true
+	resolver := &fakeResolver{answers: map[string][]string{
+		"ip4 www.example.com.": {"203.0.113.1", "198.51.100.7"},
+		"ip6 www.example.com.": {"2001:db8::7"},
+	}}
+
+	// NO_DELETE keeps the existing A value next to the desired one, and
+	// the existing AAAA value without any IPv6 address
+	provider := &memoryProvider{rrsets: map[string][]string{
+		"www.example.com. A":    {"198.51.100.7"},
+		"www.example.com. AAAA": {"2001:db8::7"},
+	}}
+	collector := newSyncCollector()
+	if err := updateDNSRecord(context.Background(), collector, provider, config, "example.com.", "www.example.com.", []string{"203.0.113.1"}, nil); err != nil {
+		t.Fatalf("updateDNSRecord() error = %v", err)
+	}
+
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(time.Hour)} {
+		if got := verifyLiveRecords(context.Background(), resolver, collector, config, at); len(got) != 0 {
+			t.Errorf("verifyLiveRecords() = %v, want the kept values expected", got)
+		}
+	}
+
+	// Records the sync did not write are not checked
+	intendedRecords = nil
+	if got := verifyLiveRecords(context.Background(), resolver, newSyncCollector(), config, now); len(got) != 0 || len(intendedRecords) != 0 {
+		t.Errorf("verifyLiveRecords() = %v with checks %v, want nothing checked", got, intendedRecords)
+	}
+}
+
+func TestLookupRecordError(t *testing.T) {
+	resolver := &fakeResolver{failing: map[string]bool{"www.example.com.": true}}
+	var dnsErr *net.DNSError
+	if _, err := lookupRecord(context.Background(), resolver, "www.example.com.", "A"); !errors.As(err, &dnsErr) || !dnsErr.IsTimeout {
+		t.Errorf("lookupRecord() error = %v, want the timeout", err)
+	}
+}
+
+func TestParseVerifyResolver(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.53":           "192.0.2.53:53",
+		"192.0.2.53:5353":      "192.0.2.53:5353",
+		"ns1.example.com":      "ns1.example.com:53",
+		"2001:db8::53":         "[2001:db8::53]:53",
+		"[2001:db8::53]":       "[2001:db8::53]:53",
+		"[2001:db8::53]:5353":  "[2001:db8::53]:5353",
+		"ns1.example.com:5353": "ns1.example.com:5353",
+	}
+	for address, want := range tests {
+		if got := parseVerifyResolver(address); got != want {
+			t.Errorf("parseVerifyResolver(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestLoadConfigVerifyDNS(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("VERIFY_DNS", "true")
+	t.Setenv("VERIFY_RESOLVER", "192.0.2.53")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.VerifyDNS || config.VerifyResolver != "192.0.2.53:53" {
+		t.Errorf("VerifyDNS = %v, VerifyResolver = %q, want true and 192.0.2.53:53", config.VerifyDNS, config.VerifyResolver)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultWatchRetryInterval is how long to wait before re-establishing a
+// node watch after it closes or fails.
+const DefaultWatchRetryInterval = 5 * time.Second
+
+// watchNodes watches node objects and signals on trigger whenever a node's
+// external IP, opt-out, priority or TTL annotation, EXCLUDE_TAINTS taint, or
+// the status the sync reads from it changes, or a node carrying one is added
+// or removed. It re-establishes the watch until ctx is cancelled.
+func watchNodes(ctx context.Context, clientset kubernetes.Interface, config *Config, trigger chan<- struct{}) {
+	// Last seen state per node, kept across re-watches so that the
+	// initial ADDED events of a new watch don't cause spurious resyncs.
+	known := make(map[string]string)
+
+	for {
+		if err := runNodeWatch(ctx, clientset, config, known, trigger); err != nil {
+			slog.Error("Node watch failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(DefaultWatchRetryInterval):
+			slog.Info("Re-establishing node watch")
+		}
+	}
+}
+
+func runNodeWatch(ctx context.Context, clientset kubernetes.Interface, config *Config, known map[string]string, trigger chan<- struct{}) error {
+	watcher, err := clientset.CoreV1().Nodes().Watch(ctx, metav1.ListOptions{
+		LabelSelector: config.NodeSelector,
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	watchedKeys := append([]string{config.ExcludeAnnotation, PriorityAnnotation, TTLAnnotation}, config.AnnotationKeys...)
+	if config.ManageTXT {
+		watchedKeys = append(watchedKeys, config.TXTAnnotation)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				slog.Info("Node watch channel closed")
+				return nil
+			}
+
+			node, isNode := event.Object.(*corev1.Node)
+			if !isNode {
+				if event.Type == watch.Error {
+					slog.Warn("Node watch returned an error event", "object", event.Object)
+				}
+				continue
+			}
+
+			if nodeStateChanged(known, event.Type, node, config, watchedKeys...) {
+				slog.Info("Node DNS state changed, triggering sync", "node", node.Name, "event", event.Type)
+				select {
+				case trigger <- struct{}{}:
+				default:
+					// A sync is already pending
+				}
+			}
+		}
+	}
+}
+
+// nodeStateChanged records the node's current values of the given
+// annotations, its taints with one of EXCLUDE_TAINTS, and the parts of its
+// status the sync reads in known, and reports whether they differ from the
+// previously recorded state. The status counts with IP_SOURCE=status or
+// both, for the addresses of the selected ADDRESS_TYPES, and with
+// EXCLUDE_NOTREADY, for the Ready condition and cordoning.
+func nodeStateChanged(known map[string]string, eventType watch.EventType, node *corev1.Node, config *Config, annotationKeys ...string) bool {
+	previous, seen := known[node.Name]
+
+	if eventType == watch.Deleted {
+		delete(known, node.Name)
+		return seen && previous != ""
+	}
+
+	var values []string
+	for _, key := range annotationKeys {
+		if value := node.Annotations[key]; value != "" {
+			values = append(values, key+"="+value)
+		}
+	}
+	if taint := excludingTaint(node, config.ExcludeTaints); taint != "" {
+		values = append(values, "taint "+taint)
+	}
+	if hasIPSource(config.IPSource, IPSourceStatus) {
+		if addressType, addresses := nodeStatusAddresses(node, config.AddressTypes); len(addresses) > 0 {
+			values = append(values, "status "+addressType+"="+strings.Join(addresses, ","))
+		}
+	}
+	if config.ExcludeNotReady {
+		if reason := nodeExclusionReason(node); reason != "" {
+			values = append(values, "excluded "+reason)
+		}
+	}
+	current := strings.Join(values, "\n")
+	known[node.Name] = current
+
+	if !seen {
+		return current != ""
+	}
+	return previous != current
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testNode(name, externalIPs string) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if externalIPs != "" {
+		node.Annotations = map[string]string{ExternalIPAnnotation: externalIPs}
+	}
+	return node
+}
+
+func TestNodeStateChanged(t *testing.T) {
+	known := make(map[string]string)
+
+	steps := []struct {
+		name      string
+		eventType watch.EventType
+		node      *corev1.Node
+		expected  bool
+	}{
+		{"New node with annotation", watch.Added, testNode("node1", "1.2.3.4"), true},
+		{"New node without annotation", watch.Added, testNode("node2", ""), false},
+		{"Re-added with same annotation", watch.Added, testNode("node1", "1.2.3.4"), false},
+		{"Unrelated modification", watch.Modified, testNode("node1", "1.2.3.4"), false},
+		{"Annotation changed", watch.Modified, testNode("node1", "5.6.7.8"), true},
+		{"Annotation added", watch.Modified, testNode("node2", "9.9.9.9"), true},
+		{"Annotation removed", watch.Modified, testNode("node2", ""), true},
+		{"Node without annotation deleted", watch.Deleted, testNode("node2", ""), false},
+		{"Node with annotation deleted", watch.Deleted, testNode("node1", "5.6.7.8"), true},
+	}
+
+	for _, step := range steps {
+		if result := nodeStateChanged(known, step.eventType, step.node, &Config{}, ExternalIPAnnotation); result != step.expected {
+			t.Errorf("%s: nodeStateChanged() = %v, want %v", step.name, result, step.expected)
+		}
+	}
+
+	if len(known) != 0 {
+		t.Errorf("expected all nodes to be forgotten after deletion, got %v", known)
+	}
+}
+
+func TestNodeStateChangedOptOut(t *testing.T) {
+	known := make(map[string]string)
+	node := testNode("node1", "1.2.3.4")
+	nodeStateChanged(known, watch.Added, node, &Config{}, ExternalIPAnnotation, ExcludeAnnotation)
+
+	optedOut := testNode("node1", "1.2.3.4")
+	optedOut.Annotations[ExcludeAnnotation] = "true"
+	if !nodeStateChanged(known, watch.Modified, optedOut, &Config{}, ExternalIPAnnotation, ExcludeAnnotation) {
+		t.Error("setting the opt-out annotation should trigger a sync")
+	}
+}
+
+func TestNodeStateChangedTaint(t *testing.T) {
+	known := make(map[string]string)
+	config := &Config{ExcludeTaints: []string{"node-role.kubernetes.io/control-plane"}}
+	nodeStateChanged(known, watch.Added, testNode("node1", "1.2.3.4"), config, ExternalIPAnnotation)
+
+	tainted := testNode("node1", "1.2.3.4")
+	tainted.Spec.Taints = []corev1.Taint{{Key: "node.kubernetes.io/unreachable", Effect: corev1.TaintEffectNoExecute}}
+	if nodeStateChanged(known, watch.Modified, tainted, config, ExternalIPAnnotation) {
+		t.Error("a taint not in EXCLUDE_TAINTS should not trigger a sync")
+	}
+
+	tainted.Spec.Taints = append(tainted.Spec.Taints, corev1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule})
+	if !nodeStateChanged(known, watch.Modified, tainted, config, ExternalIPAnnotation) {
+		t.Error("adding an excluding taint should trigger a sync")
+	}
+}
+
+// withStatus returns node reporting the given addresses and Ready status.
+func withStatus(node *corev1.Node, ready corev1.ConditionStatus, addresses ...corev1.NodeAddress) *corev1.Node {
+	node.Status.Addresses = addresses
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}
+	return node
+}
+
+func TestNodeStateChangedStatus(t *testing.T) {
+	known := make(map[string]string)
+	config := &Config{IPSource: IPSourceBoth, AddressTypes: []string{"ExternalIP"}, ExcludeNotReady: true}
+	external := corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.1"}
+	internal := corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}
+
+	steps := []struct {
+		name     string
+		node     *corev1.Node
+		expected bool
+	}{
+		{"New node with a status address", withStatus(testNode("node1", ""), corev1.ConditionTrue, external), true},
+		{"Unselected address type changed", withStatus(testNode("node1", ""), corev1.ConditionTrue, external, internal), false},
+		{"Selected status address changed", withStatus(testNode("node1", ""), corev1.ConditionTrue, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.2"}, internal), true},
+		{"Node became NotReady", withStatus(testNode("node1", ""), corev1.ConditionFalse, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.2"}, internal), true},
+		{"Node became Ready", withStatus(testNode("node1", ""), corev1.ConditionTrue, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.2"}, internal), true},
+	}
+	for _, step := range steps {
+		if result := nodeStateChanged(known, watch.Modified, step.node, config, ExternalIPAnnotation); result != step.expected {
+			t.Errorf("%s: nodeStateChanged() = %v, want %v", step.name, result, step.expected)
+		}
+	}
+
+	cordoned := withStatus(testNode("node1", ""), corev1.ConditionTrue, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.2"}, internal)
+	cordoned.Spec.Unschedulable = true
+	if !nodeStateChanged(known, watch.Modified, cordoned, config, ExternalIPAnnotation) {
+		t.Error("cordoning the node should trigger a sync with EXCLUDE_NOTREADY")
+	}
+
+	// Without the status source and EXCLUDE_NOTREADY, the status is ignored
+	known = make(map[string]string)
+	config = &Config{IPSource: IPSourceAnnotation, AddressTypes: []string{"ExternalIP"}}
+	nodeStateChanged(known, watch.Added, withStatus(testNode("node1", "1.2.3.4"), corev1.ConditionTrue, external), config, ExternalIPAnnotation)
+	if nodeStateChanged(known, watch.Modified, withStatus(testNode("node1", "1.2.3.4"), corev1.ConditionFalse, internal), config, ExternalIPAnnotation) {
+		t.Error("status changes should not trigger a sync when the sync does not read them")
+	}
+}
+
+func TestRunNodeWatchStatusAddressChange(t *testing.T) {
+	node := withStatus(testNode("node1", ""), corev1.ConditionTrue, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.1"})
+	clientset := fake.NewSimpleClientset()
+	config := &Config{IPSource: IPSourceStatus, AddressTypes: []string{"ExternalIP"}, ExcludeAnnotation: ExcludeAnnotation}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trigger := make(chan struct{}, 1)
+	go runNodeWatch(ctx, clientset, config, make(map[string]string), trigger)
+
+	// Creating the node until the watch sees it also proves it is established
+	for created := false; !created; {
+		if _, err := clientset.CoreV1().Nodes().Create(ctx, node.DeepCopy(), metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-trigger:
+			created = true
+		case <-time.After(50 * time.Millisecond):
+			if err := clientset.CoreV1().Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	node.Status.Addresses[0].Address = "203.0.113.2"
+	if _, err := clientset.CoreV1().Nodes().UpdateStatus(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-trigger:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no sync triggered after a status address change")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultWebhookThreshold is how many consecutive sync failures trigger
+	// a webhook notification.
+	DefaultWebhookThreshold = 3
+	// webhookTimeout bounds a single webhook delivery.
+	webhookTimeout = 10 * time.Second
+)
+
+// webhookPayload is the JSON body posted to WEBHOOK_URL. The text field
+// makes it directly usable with Slack-compatible incoming webhooks.
+type webhookPayload struct {
+	Text      string    `json:"text"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Records   []string  `json:"records"`
+	Failures  int       `json:"failures"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier posts to a webhook once syncs have failed threshold times in a
+// row, and again when they recover. A nil *Notifier does nothing.
+type Notifier struct {
+	url       string
+	threshold int
+	records   []string
+	client    *http.Client
+
+	failures int
+	alerted  bool
+}
+
+// newNotifier returns nil when no webhook URL is configured.
+func newNotifier(config *Config) *Notifier {
+	if config.WebhookURL == "" {
+		return nil
+	}
+	return &Notifier{
+		url:       config.WebhookURL,
+		threshold: config.WebhookThreshold,
+		records:   config.DNSRecords,
+		client:    &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// reconfigured returns the notifier for a reloaded config, with its URL,
+// threshold and records replaced. The failure count and alert state carry
+// over, so a reload during an outage neither repeats nor loses the alert
+// and the recovery notice.
+func (n *Notifier) reconfigured(config *Config) *Notifier {
+	next := newNotifier(config)
+	if n == nil || next == nil {
+		return next
+	}
+	next.failures, next.alerted = n.failures, n.alerted
+	return next
+}
+
+// observe records the outcome of a sync and sends a notification when the
+// failure threshold is reached or syncs succeed again after an alert.
+func (n *Notifier) observe(ctx context.Context, err error) {
+	if n == nil {
+		return
+	}
+
+	if err != nil {
+		n.failures++
+		// At or past the threshold, as a reload may have lowered it
+		if n.failures >= n.threshold && !n.alerted {
+			n.alerted = true
+			n.send(ctx, webhookPayload{
+				Text:     fmt.Sprintf("DNS sync for %s has failed %d times in a row: %v", strings.Join(n.records, ", "), n.failures, err),
+				Status:   "failing",
+				Error:    err.Error(),
+				Failures: n.failures,
+			})
+		}
+		return
+	}
+
+	if n.alerted {
+		n.send(ctx, webhookPayload{
+			Text:     fmt.Sprintf("DNS sync for %s recovered after %d failures", strings.Join(n.records, ", "), n.failures),
+			Status:   "recovered",
+			Failures: n.failures,
+		})
+	}
+	n.failures = 0
+	n.alerted = false
+}
+
+// send delivers a payload, logging rather than returning failures so a
+// broken webhook never affects syncing.
+func (n *Notifier) send(ctx context.Context, payload webhookPayload) {
+	payload.Records = n.records
+	payload.Timestamp = time.Now().UTC()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Warn("Failed to encode webhook payload", "error", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("Failed to create webhook request", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		slog.Warn("Failed to send webhook notification", "status", payload.Status, "error", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		slog.Warn("Webhook rejected notification", "status", payload.Status, "http_status", resp.Status)
+		return
+	}
+	slog.Info("Sent webhook notification", "status", payload.Status, "failures", payload.Failures)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotifierThresholdAndRecovery(t *testing.T) {
+	var received []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	notifier := newNotifier(&Config{WebhookURL: server.URL, WebhookThreshold: 2, DNSRecords: []string{"cluster.example.com."}})
+	ctx := context.Background()
+	boom := errors.New("powerdns unavailable")
+
+	notifier.observe(ctx, boom)
+	if len(received) != 0 {
+		t.Fatalf("notified after 1 failure, want threshold of 2")
+	}
+	notifier.observe(ctx, boom)
+	notifier.observe(ctx, boom)
+	if len(received) != 1 || received[0].Status != "failing" || received[0].Failures != 2 || received[0].Error != boom.Error() {
+		t.Fatalf("after 3 failures received %+v, want one failing notification", received)
+	}
+
+	notifier.observe(ctx, nil)
+	notifier.observe(ctx, nil)
+	if len(received) != 2 || received[1].Status != "recovered" || received[1].Failures != 3 {
+		t.Fatalf("after recovery received %+v, want one recovered notification", received)
+	}
+	if len(received[1].Records) != 1 || received[1].Timestamp.IsZero() {
+		t.Errorf("recovery payload = %+v, want records and timestamp", received[1])
+	}
+}
+
+func TestNotifierReconfigured(t *testing.T) {
+	received := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		received[r.URL.Path] = append(received[r.URL.Path], payload.Status)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	boom := errors.New("powerdns unavailable")
+	notifier := newNotifier(&Config{WebhookURL: server.URL + "/old", WebhookThreshold: 5})
+	for i := 0; i < 3; i++ {
+		notifier.observe(ctx, boom)
+	}
+
+	// The reload lowers the threshold below the failures so far, and the
+	// count carries over, so the next failure alerts at the new URL
+	notifier = notifier.reconfigured(&Config{WebhookURL: server.URL + "/new", WebhookThreshold: 2})
+	notifier.observe(ctx, boom)
+	notifier.observe(ctx, boom)
+	notifier = notifier.reconfigured(&Config{WebhookURL: server.URL + "/new", WebhookThreshold: 2})
+	notifier.observe(ctx, nil)
+
+	if got := received["/new"]; len(got) != 2 || got[0] != "failing" || got[1] != "recovered" {
+		t.Errorf("notifications at the new URL = %v, want one failing and one recovered", got)
+	}
+	if got := received["/old"]; len(got) != 0 {
+		t.Errorf("notifications at the old URL = %v, want none", got)
+	}
+
+	if notifier.reconfigured(&Config{}) != nil {
+		t.Error("reconfigured() without WEBHOOK_URL should return nil")
+	}
+}
+
+func TestNotifierDisabled(t *testing.T) {
+	notifier := newNotifier(&Config{})
+	if notifier != nil {
+		t.Fatal("newNotifier() without WEBHOOK_URL should return nil")
+	}
+	notifier.observe(context.Background(), errors.New("ignored"))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+// finishZoneUpdate rectifies the zone and sends NOTIFYs to its secondaries,
+// as enabled by RECTIFY_ZONE and NOTIFY_SECONDARIES. It is called after
+// records changed. Failures are only logged: the records themselves are
+// already up to date, and the next change retries.
+func finishZoneUpdate(ctx context.Context, target *PowerDNSTarget, options zoneFinishOptions, zone string) {
+	if options.rectify {
+		if options.dryRun {
+			slog.Info("[dry-run] Would rectify zone", "target", target.URL, "zone", zone)
+		} else if err := rectifyZone(ctx, target, zone); err != nil {
+			slog.Warn("Failed to rectify zone", "target", target.URL, "zone", zone, "kind", powerDNSErrorKind(err), "error", err)
+		} else {
+			slog.Info("Rectified zone", "target", target.URL, "zone", zone)
+		}
+	}
+
+	if options.notify {
+		if options.dryRun {
+			slog.Info("[dry-run] Would notify secondaries", "target", target.URL, "zone", zone)
+			return
+		}
+		_, err := target.Client.Zones.Notify(ctx, zone)
+		metrics.observePowerDNSRequest("notify", err)
+		if err != nil {
+			slog.Warn("Failed to notify secondaries", "target", target.URL, "zone", zone, "kind", powerDNSErrorKind(err), "error", err)
+			return
+		}
+		slog.Info("Notified secondaries", "target", target.URL, "zone", zone)
+	}
+}
+
+// parseZoneKind returns the PowerDNS zone kind named by ZONE_KIND, in any
+// case, defaulting to Native.
+func parseZoneKind(kind string) (string, error) {
+	switch {
+	case kind == "", strings.EqualFold(kind, string(powerdns.NativeZoneKind)):
+		return string(powerdns.NativeZoneKind), nil
+	case strings.EqualFold(kind, string(powerdns.MasterZoneKind)):
+		return string(powerdns.MasterZoneKind), nil
+	default:
+		return "", fmt.Errorf("invalid ZONE_KIND %q: must be %s or %s", kind, powerdns.NativeZoneKind, powerdns.MasterZoneKind)
+	}
+}
+
+// createZone creates a missing zone with CREATE_ZONE, of kind ZONE_KIND and
+// with ZONE_NAMESERVERS as its NS records. SOA-EDIT-API is set so that the
+// serial is bumped on every change made through the API.
+func createZone(ctx context.Context, target *PowerDNSTarget, config *Config, zone string) error {
+	if config.DryRun {
+		slog.Info("[dry-run] Would create zone", "target", target.URL, "zone", zone, "kind", config.ZoneKind, "nameservers", config.ZoneNameservers)
+		return fmt.Errorf("zone %s does not exist and is not created in dry-run mode", zone)
+	}
+
+	slog.Info("Zone does not exist, creating it", "target", target.URL, "zone", zone, "kind", config.ZoneKind, "nameservers", config.ZoneNameservers)
+	_, err := target.Client.Zones.Add(ctx, &powerdns.Zone{
+		Name:        powerdns.String(zone),
+		Kind:        powerdns.ZoneKindPtr(powerdns.ZoneKind(config.ZoneKind)),
+		Nameservers: config.ZoneNameservers,
+		SOAEditAPI:  powerdns.String("DEFAULT"),
+	})
+	metrics.observePowerDNSRequest("create_zone", err)
+	if err != nil {
+		return fmt.Errorf("failed to create zone: %w", err)
+	}
+	slog.Info("Created zone", "target", target.URL, "zone", zone)
+	return nil
+}
+
+// rectifyZone asks PowerDNS to rectify the zone, which recomputes the
+// DNSSEC ordering and auth data after records changed. go-powerdns has no
+// call for it, so the request is built here like the library does.
+func rectifyZone(ctx context.Context, target *PowerDNSTarget, zone string) error {
+	client := target.Client
+	apiURL := url.URL{
+		Scheme: client.Scheme,
+		Host:   net.JoinHostPort(client.Hostname, client.Port),
+		Path:   fmt.Sprintf("/api/v1/servers/%s/zones/%s/rectify", client.VHost, validateDNSZone(zone)),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, apiURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", target.apiKey)
+	for key, value := range client.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := target.httpClient.Do(req)
+	metrics.observePowerDNSRequest("rectify", err)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &powerdns.Error{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Message:    strings.TrimSpace(string(body)),
+		}
+	}
+	return nil
+}
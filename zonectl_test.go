@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestFinishZoneUpdate(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-API-Key"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result": "ok"}`))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		PowerDNSURLs:      []string{server.URL},
+		PowerDNSServerID:  "localhost",
+		PowerDNSAPIKey:    "secret",
+		NotifySecondaries: true,
+		RectifyZone:       true,
+	}
+	targets, err := newPowerDNSTargets(config)
+	if err != nil {
+		t.Fatalf("newPowerDNSTargets() error = %v", err)
+	}
+
+	finishZoneUpdate(context.Background(), targets[0], finishOptions(config), "example.com.")
+
+	want := []string{
+		"PUT /api/v1/servers/localhost/zones/example.com./rectify secret",
+		"PUT /api/v1/servers/localhost/zones/example.com./notify secret",
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, requests[i], want[i])
+		}
+	}
+
+	requests = nil
+	config.DryRun = true
+	finishZoneUpdate(context.Background(), targets[0], finishOptions(config), "example.com.")
+	if len(requests) != 0 {
+		t.Errorf("dry run sent requests %v, want none", requests)
+	}
+}
+
+func TestRectifyZoneError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Could not find domain", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	targets, err := newPowerDNSTargets(&Config{PowerDNSURLs: []string{server.URL}, PowerDNSServerID: "localhost"})
+	if err != nil {
+		t.Fatalf("newPowerDNSTargets() error = %v", err)
+	}
+
+	err = rectifyZone(context.Background(), targets[0], "missing.example.")
+	if !isNotFound(err) {
+		t.Errorf("rectifyZone() error = %v, want a not found error", err)
+	}
+}
+
+// finishingProvider is a memoryProvider recording the zones it was asked to
+// finish.
+type finishingProvider struct {
+	memoryProvider
+	finished []string
+}
+
+func (p *finishingProvider) FinishZoneUpdate(ctx context.Context, options zoneFinishOptions, zone string) {
+	p.finished = append(p.finished, zone)
+}
+
+func TestUpdateDNSRecordsFinishesChangedZones(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		provider := &finishingProvider{memoryProvider: memoryProvider{rrsets: map[string][]string{}}}
+		config := newUpdateTestConfig()
+		config.DryRun = dryRun
+		ips, _ := parseIPAddresses("192.0.2.1")
+
+		if _, err := updateDNSRecords(context.Background(), []DNSProvider{provider}, config, ips); err != nil {
+			t.Fatalf("updateDNSRecords() error = %v", err)
+		}
+		if len(provider.finished) != 1 || provider.finished[0] != "example.com." {
+			t.Errorf("dry run %v: finished zones = %v, want example.com. after a change", dryRun, provider.finished)
+		}
+
+		// Nothing changes on the next sync, so the zone is left alone
+		if !dryRun {
+			provider.finished = nil
+			if _, err := updateDNSRecords(context.Background(), []DNSProvider{provider}, config, ips); err != nil {
+				t.Fatalf("updateDNSRecords() error = %v", err)
+			}
+			if len(provider.finished) != 0 {
+				t.Errorf("finished zones = %v, want none without changes", provider.finished)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ZoneRecords is a zone and the record names published in it.
+type ZoneRecords struct {
+	Zone    string
+	Records []string
+}
+
+// parseZoneRecords parses ZONE_RECORDS, a semicolon-separated list of
+// zone=record[,record...] entries, e.g.
+// "internal.=records.internal;example.org=a.example.org,b.example.org".
+func parseZoneRecords(value string) ([]ZoneRecords, error) {
+	var pairs []ZoneRecords
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		zone, records, ok := strings.Cut(entry, "=")
+		zone = strings.TrimSpace(zone)
+		if !ok || zone == "" {
+			return nil, fmt.Errorf("entry %q must have the form zone=record[,record...]", entry)
+		}
+
+		pair := ZoneRecords{Zone: validateDNSZone(zone), Records: parseDNSRecords(records)}
+		if len(pair.Records) == 0 {
+			return nil, fmt.Errorf("zone %s has no records", pair.Zone)
+		}
+		for _, record := range pair.Records {
+			if !recordInZone(record, pair.Zone) {
+				return nil, fmt.Errorf("record %s is not within zone %s", record, pair.Zone)
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// zoneConfigs returns one config per zone/record pair to sync: config itself
+// for DNS_ZONE and DNS_RECORD, followed by a copy for each ZONE_RECORDS
+// entry. The CNAME, PTR, per-family and per-node records belong to the
+// primary zone, so the copies do not manage them.
+func zoneConfigs(config *Config) []*Config {
+	configs := []*Config{config}
+	for _, pair := range config.ExtraZones {
+		zoneConfig := *config
+		zoneConfig.DNSZone = pair.Zone
+		zoneConfig.DNSRecords = pair.Records
+		zoneConfig.CNAME = ""
+		zoneConfig.DNSRecordA = ""
+		zoneConfig.DNSRecordAAAA = ""
+		zoneConfig.ManagePTR = false
+		zoneConfig.PerNodeRecords = ""
+		configs = append(configs, &zoneConfig)
+	}
+	return configs
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseZoneRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []ZoneRecords
+		wantErr bool
+	}{
+		{name: "empty", value: ""},
+		{
+			name:  "single pair",
+			value: "internal=records.internal",
+			want:  []ZoneRecords{{Zone: "internal.", Records: []string{"records.internal."}}},
+		},
+		{
+			name:  "multiple pairs",
+			value: " internal.=records.internal ; example.org=a.example.org,b.example.org ;",
+			want: []ZoneRecords{
+				{Zone: "internal.", Records: []string{"records.internal."}},
+				{Zone: "example.org.", Records: []string{"a.example.org.", "b.example.org."}},
+			},
+		},
+		{name: "missing separator", value: "internal", wantErr: true},
+		{name: "missing zone", value: "=records.internal", wantErr: true},
+		{name: "no records", value: "internal=", wantErr: true},
+		{name: "record outside zone", value: "internal=records.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseZoneRecords(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseZoneRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseZoneRecords() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZoneConfigs(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DNS_CNAME", "www.example.com")
+	t.Setenv("MANAGE_PTR", "true")
+	t.Setenv("ZONE_RECORDS", "internal=records.internal")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+
+	configs := zoneConfigs(config)
+	if len(configs) != 2 {
+		t.Fatalf("zoneConfigs() returned %d configs, want 2", len(configs))
+	}
+	if configs[0] != config {
+		t.Error("zoneConfigs()[0] should be the primary config")
+	}
+
+	extra := configs[1]
+	if extra.DNSZone != "internal." || !reflect.DeepEqual(extra.DNSRecords, []string{"records.internal."}) {
+		t.Errorf("extra zone = %s %v, want internal. [records.internal.]", extra.DNSZone, extra.DNSRecords)
+	}
+	if extra.CNAME != "" || extra.ManagePTR {
+		t.Errorf("extra zone CNAME = %q, ManagePTR = %v, want neither", extra.CNAME, extra.ManagePTR)
+	}
+	if config.DNSZone != "example.com." || config.CNAME == "" {
+		t.Error("zoneConfigs() must not modify the primary config")
+	}
+}